@@ -0,0 +1,13 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// accessWriteOK access(2) 的 W_OK
+const accessWriteOK = 0x2
+
+// dirWritable 通过 access(2) 检查当前用户能否写入目录，不创建任何文件
+func dirWritable(dir string) error {
+	return syscall.Access(dir, accessWriteOK)
+}
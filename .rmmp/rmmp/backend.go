@@ -0,0 +1,64 @@
+package main
+
+// RootBackend Root管理器后端接口
+// 每种Root方案（Magisk、APatch、KernelSU等）实现该接口，
+// RMMD 只通过该接口操作模块，新增Root方案时无需修改 RMMD
+type RootBackend interface {
+	// Name 返回Root方案名称，如 "Magisk"
+	Name() string
+	// Version 返回Root管理器版本
+	Version() (string, error)
+	// ListModules 列出已安装的模块
+	ListModules() ([]ModuleInfo, error)
+	// InstallModule 安装模块zip包（绝对路径）
+	InstallModule(zipPath string) error
+	// UninstallModule 将模块标记为删除
+	UninstallModule(moduleID string) error
+	// UndoUninstallModule 撤销尚未生效的删除标记
+	UndoUninstallModule(moduleID string) error
+	// EnableModule 启用模块
+	EnableModule(moduleID string) error
+	// DisableModule 禁用模块
+	DisableModule(moduleID string) error
+}
+
+// RootBackendFactory 描述一种可被自动检测的Root后端
+type RootBackendFactory struct {
+	// Name Root方案名称
+	Name string
+	// Marker 标志该Root环境的目录，Detect 为空时检查该目录是否存在
+	Marker string
+	// Detect 判断当前设备是否为该Root环境，为空时使用 Marker
+	Detect func(r *RMMD) bool
+	// Binary 管理器二进制文件（设备路径）
+	Binary string
+	// Probe 执行管理器二进制获取版本和运行状态，为空时只检查 Marker
+	Probe func(binary string) (RootVersion, error)
+	// MinVersionCode 支持的最低版本号，0 表示不限制
+	MinVersionCode int
+	// Fallback 只在没有检测到其他Root环境时使用
+	Fallback bool
+	// New 创建后端实例
+	New func(r *RMMD) RootBackend
+}
+
+// rootBackends 已注册的Root后端，多个环境同时存在时按该顺序优先
+var rootBackends = []RootBackendFactory{
+	magiskBackendFactory,
+	apatchBackendFactory,
+	kernelSUBackendFactory,
+	nativeBackendFactory,
+}
+
+// detect 判断设备上是否存在该Root环境的痕迹
+func (f RootBackendFactory) detect(r *RMMD) bool {
+	if f.Detect != nil {
+		return f.Detect(r)
+	}
+	return f.Marker != "" && r.dirExists(f.Marker)
+}
+
+// RegisterRootBackend 注册新的Root后端，排在已有后端之后检测
+func RegisterRootBackend(factory RootBackendFactory) {
+	rootBackends = append(rootBackends, factory)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// apatchBackendFactory APatch后端注册信息
+var apatchBackendFactory = RootBackendFactory{
+	Name:   "APatch",
+	Marker: "/data/adb/ap",
+	Binary: "/data/adb/apd",
+	Probe:  probeAPatch,
+	New: func(r *RMMD) RootBackend {
+		// apd 没有 restore 子命令，撤销卸载时直接删除标记文件
+		return &CommandBackend{rmmd: r, name: "APatch", binaryPath: "/data/adb/apd"}
+	},
+}
+
+// kernelSUBackendFactory KernelSU后端注册信息
+var kernelSUBackendFactory = RootBackendFactory{
+	Name:   "KernelSU",
+	Marker: "/data/adb/ksu",
+	Binary: "/data/adb/ksud",
+	Probe:  probeKernelSU,
+	New: func(r *RMMD) RootBackend {
+		return &CommandBackend{
+			rmmd:            r,
+			name:            "KernelSU",
+			binaryPath:      "/data/adb/ksud",
+			restoreCommand:  "restore",
+			listJSONVersion: 10940,
+		}
+	},
+}
+
+// probeKernelSU 通过 ksud --version 获取版本，ksud debug version 获取内核版本号
+// 内核版本号能读到说明内核中的KernelSU正在工作；KernelSU Next 和 SukiSU 的版本名中带有分支名
+func probeKernelSU(binary string) (RootVersion, error) {
+	output, err := exec.Command(binary, "--version").Output()
+	if err != nil {
+		return RootVersion{}, fmt.Errorf("执行 %s --version 失败: %v", binary, err)
+	}
+
+	raw := strings.TrimSpace(string(output))
+	version := RootVersion{Variant: "KernelSU", Version: strings.TrimSpace(strings.TrimPrefix(raw, "ksud"))}
+	lower := strings.ToLower(raw)
+	switch {
+	case strings.Contains(lower, "next"):
+		version.Variant = "KernelSU Next"
+	case strings.Contains(lower, "suki"):
+		version.Variant = "SukiSU"
+	}
+
+	// 输出形如 "Kernel Version: 11986"
+	if output, err := exec.Command(binary, "debug", "version").Output(); err == nil {
+		fields := strings.Fields(string(output))
+		if len(fields) > 0 {
+			version.VersionCode, _ = parseIntString(fields[len(fields)-1])
+		}
+	}
+	version.Active = version.VersionCode > 0
+	return version, nil
+}
+
+// probeAPatch 通过 apd --version 获取版本，输出形如 "apd 10983"
+func probeAPatch(binary string) (RootVersion, error) {
+	output, err := exec.Command(binary, "--version").Output()
+	if err != nil {
+		return RootVersion{}, fmt.Errorf("执行 %s --version 失败: %v", binary, err)
+	}
+
+	raw := strings.TrimSpace(string(output))
+	version := RootVersion{Variant: "APatch", Version: strings.TrimSpace(strings.TrimPrefix(raw, "apd"))}
+	version.VersionCode, _ = parseIntString(version.Version)
+	// apd 没有常驻进程，能执行即视为可用
+	version.Active = true
+	return version, nil
+}
+
+// CommandBackend 通过 `<binary> module <子命令>` 管理模块的后端（APatch、KernelSU）
+type CommandBackend struct {
+	rmmd       *RMMD
+	name       string
+	binaryPath string
+	// restoreCommand 撤销卸载的子命令，为空时直接删除 remove 标记
+	restoreCommand string
+	// listJSONVersion module list 开始输出JSON的版本号，0 表示不按版本判断
+	listJSONVersion int
+}
+
+// Name 返回Root方案名称
+func (b *CommandBackend) Name() string {
+	return b.name
+}
+
+// Version 返回Root管理器版本
+func (b *CommandBackend) Version() (string, error) {
+	output, err := exec.Command(b.binaryPath, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("获取%s版本失败: %v", b.name, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ListModules 通过 module list 列出模块
+// 管理器版本过低或 module list 无法使用时改为扫描模块目录
+func (b *CommandBackend) ListModules() ([]ModuleInfo, error) {
+	// sysroot 中的二进制无法在本机执行，直接扫描模块目录
+	if sysroot != "" || !b.listSupportsJSON() {
+		return b.rmmd.listMagiskModules()
+	}
+
+	modules, err := b.listByCommand()
+	if err != nil {
+		fmt.Fprintf(msgOut, "⚠️  %v，改为扫描 %s\n", err, modulesDir)
+		return b.rmmd.listMagiskModules()
+	}
+	return modules, nil
+}
+
+// listSupportsJSON 管理器的 module list 是否输出JSON，版本号未知时先尝试执行
+func (b *CommandBackend) listSupportsJSON() bool {
+	if !b.rmmd.fileExists(b.binaryPath) {
+		return false
+	}
+	versionCode := b.rmmd.rootVersion.VersionCode
+	return b.listJSONVersion == 0 || versionCode == 0 || versionCode >= b.listJSONVersion
+}
+
+// listByCommand 执行 module list 并解析JSON输出
+func (b *CommandBackend) listByCommand() ([]ModuleInfo, error) {
+	output, err := exec.Command(b.binaryPath, "module", "list").Output()
+	if err != nil {
+		return nil, fmt.Errorf("执行 %s module list 失败: %v", b.name, err)
+	}
+
+	var modules []ModuleInfo
+	if err := json.Unmarshal(output, &modules); err != nil {
+		return nil, fmt.Errorf("解析 %s module list 输出失败: %v", b.name, err)
+	}
+	return modules, nil
+}
+
+// InstallModule 通过 module install 安装模块
+func (b *CommandBackend) InstallModule(zipPath string) error {
+	return b.run("install", zipPath)
+}
+
+// UninstallModule 通过 module uninstall 卸载模块
+func (b *CommandBackend) UninstallModule(moduleID string) error {
+	if sysroot != "" {
+		return b.writeMarker(moduleID, "remove", true)
+	}
+	return b.run("uninstall", moduleID)
+}
+
+// UndoUninstallModule 撤销卸载
+func (b *CommandBackend) UndoUninstallModule(moduleID string) error {
+	if sysroot != "" || b.restoreCommand == "" {
+		return b.writeMarker(moduleID, "remove", false)
+	}
+	return b.run(b.restoreCommand, moduleID)
+}
+
+// EnableModule 通过 module enable 启用模块
+func (b *CommandBackend) EnableModule(moduleID string) error {
+	if sysroot != "" {
+		return b.writeMarker(moduleID, "disable", false)
+	}
+	return b.run("enable", moduleID)
+}
+
+// DisableModule 通过 module disable 禁用模块
+func (b *CommandBackend) DisableModule(moduleID string) error {
+	if sysroot != "" {
+		return b.writeMarker(moduleID, "disable", true)
+	}
+	return b.run("disable", moduleID)
+}
+
+// writeMarker 直接读写标记文件（与 apd/ksud 的行为一致）
+func (b *CommandBackend) writeMarker(moduleID, marker string, present bool) error {
+	return b.rmmd.writeMarker(filepath.Join(modulesDir, moduleID, marker), present)
+}
+
+// run 执行 module 子命令
+func (b *CommandBackend) run(subCommand, arg string) error {
+	if !b.rmmd.fileExists(b.binaryPath) {
+		return fmt.Errorf("二进制文件不存在: %s", b.binaryPath)
+	}
+
+	cmd := exec.Command(b.binaryPath, "module", subCommand, arg)
+	cmd.Stdout = msgOut
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("执行 %s module %s 失败: %v", b.name, subCommand, err)
+	}
+	return nil
+}
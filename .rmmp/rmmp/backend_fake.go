@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// FakeBackend 内存中的Root后端，不访问设备，供测试使用
+// 通过 NewRMMDWithBackend(NewFakeBackend(...)) 注入
+type FakeBackend struct {
+	mu      sync.Mutex
+	modules map[string]*ModuleInfo
+	version string
+}
+
+// NewFakeBackend 创建内存后端，可预置已安装的模块
+func NewFakeBackend(modules ...ModuleInfo) *FakeBackend {
+	b := &FakeBackend{
+		modules: make(map[string]*ModuleInfo),
+		version: "fake",
+	}
+	for i := range modules {
+		module := modules[i]
+		b.modules[module.ID] = &module
+	}
+	return b
+}
+
+// Name 返回Root方案名称
+func (b *FakeBackend) Name() string {
+	return "Fake"
+}
+
+// Version 返回后端版本
+func (b *FakeBackend) Version() (string, error) {
+	return b.version, nil
+}
+
+// ListModules 按模块ID排序返回所有模块
+func (b *FakeBackend) ListModules() ([]ModuleInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	modules := make([]ModuleInfo, 0, len(b.modules))
+	for _, module := range b.modules {
+		modules = append(modules, *module)
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].ID < modules[j].ID
+	})
+	return modules, nil
+}
+
+// InstallModule 读取zip包中的 module.prop 并记录为已安装
+func (b *FakeBackend) InstallModule(zipPath string) error {
+	info, err := (&RMMD{}).ValidateModuleZip(zipPath)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	module := moduleFromProps(info.ID, info.Props, ModuleCapabilities{})
+	b.modules[info.ID] = &module
+	return nil
+}
+
+// UninstallModule 标记模块为删除
+func (b *FakeBackend) UninstallModule(moduleID string) error {
+	return b.update(moduleID, func(m *ModuleInfo) { m.Remove = true })
+}
+
+// UndoUninstallModule 取消删除标记
+func (b *FakeBackend) UndoUninstallModule(moduleID string) error {
+	return b.update(moduleID, func(m *ModuleInfo) { m.Remove = false })
+}
+
+// EnableModule 启用模块
+func (b *FakeBackend) EnableModule(moduleID string) error {
+	return b.update(moduleID, func(m *ModuleInfo) { m.Enabled = true })
+}
+
+// DisableModule 禁用模块
+func (b *FakeBackend) DisableModule(moduleID string) error {
+	return b.update(moduleID, func(m *ModuleInfo) { m.Enabled = false })
+}
+
+// update 修改指定模块
+func (b *FakeBackend) update(moduleID string, fn func(m *ModuleInfo)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	module, ok := b.modules[moduleID]
+	if !ok {
+		return fmt.Errorf("模块未安装: %s", moduleID)
+	}
+	fn(module)
+	return nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// writeTestZip 在临时目录中创建zip，files 为文件名到内容的映射
+// 以 "->" 开头的内容写为指向其余部分的符号链接
+func writeTestZip(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "module.zip")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := zip.NewWriter(out)
+	for _, name := range names {
+		content := files[name]
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		if len(content) > 2 && content[:2] == "->" {
+			header.SetMode(os.ModeSymlink | 0777)
+			content = content[2:]
+		} else if name[len(name)-1] == '/' {
+			header.SetMode(os.ModeDir | 0755)
+		} else {
+			header.SetMode(0644)
+		}
+		f, err := w.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testModuleZip 创建一个最小的可安装模块zip
+func testModuleZip(t *testing.T, id string, versionCode int, extra map[string]string) string {
+	t.Helper()
+
+	files := map[string]string{
+		"module.prop": fmt.Sprintf("id=%s\nname=%s\nversion=v%d\nversionCode=%d\nauthor=test\ndescription=test\n",
+			id, id, versionCode, versionCode),
+		"customize.sh": "ui_print test\n",
+	}
+	for name, content := range extra {
+		files[name] = content
+	}
+	return writeTestZip(t, files)
+}
+
+func TestFakeBackendThroughRMMD(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	r := NewRMMDWithBackend(NewFakeBackend(
+		ModuleInfo{ID: "preset", Name: "preset", Version: "v1", VersionCode: 1, Enabled: true},
+	))
+
+	steps := []struct {
+		name        string
+		action      string
+		id          string
+		versionCode int
+		wantChanged bool
+		wantErr     bool
+		want        []string
+	}{
+		{name: "initial", action: "list", want: []string{"preset 1 enabled"}},
+		{
+			name: "install", action: "install", id: "demo", versionCode: 1,
+			want: []string{"demo 1 enabled", "preset 1 enabled"},
+		},
+		{
+			name: "disable", action: "disable", id: "demo", wantChanged: true,
+			want: []string{"demo 1 disabled", "preset 1 enabled"},
+		},
+		{
+			name: "disable again", action: "disable", id: "demo",
+			want: []string{"demo 1 disabled", "preset 1 enabled"},
+		},
+		{
+			name: "enable", action: "enable", id: "demo", wantChanged: true,
+			want: []string{"demo 1 enabled", "preset 1 enabled"},
+		},
+		{
+			name: "upgrade", action: "install", id: "demo", versionCode: 2,
+			want: []string{"demo 2 enabled", "preset 1 enabled"},
+		},
+		{
+			name: "uninstall", action: "uninstall", id: "preset", wantChanged: true,
+			want: []string{"demo 2 enabled", "preset 1 enabled remove"},
+		},
+		{
+			name: "undo uninstall", action: "undo-uninstall", id: "preset", wantChanged: true,
+			want: []string{"demo 2 enabled", "preset 1 enabled"},
+		},
+		{
+			name: "not installed", action: "enable", id: "missing", wantErr: true,
+			want: []string{"demo 2 enabled", "preset 1 enabled"},
+		},
+		{
+			name: "invalid id", action: "disable", id: "../demo", wantErr: true,
+			want: []string{"demo 2 enabled", "preset 1 enabled"},
+		},
+	}
+
+	for _, step := range steps {
+		var result *ModuleActionResult
+		var err error
+		switch step.action {
+		case "install":
+			err = r.InstallModule(testModuleZip(t, step.id, step.versionCode, nil), InstallOptions{ConflictPolicy: ConflictAbort})
+		case "enable":
+			result, err = r.EnableModule(step.id)
+		case "disable":
+			result, err = r.DisableModule(step.id)
+		case "uninstall":
+			result, err = r.UninstallModule(step.id)
+		case "undo-uninstall":
+			result, err = r.UndoUninstallModule(step.id)
+		}
+
+		if (err != nil) != step.wantErr {
+			t.Fatalf("%s: error = %v, wantErr %v", step.name, err, step.wantErr)
+		}
+		if result != nil && result.Changed != step.wantChanged {
+			t.Errorf("%s: changed = %v, want %v", step.name, result.Changed, step.wantChanged)
+		}
+
+		modules, err := r.ListModules()
+		if err != nil {
+			t.Fatalf("%s: ListModules: %v", step.name, err)
+		}
+		var got []string
+		for _, module := range modules {
+			state := fmt.Sprintf("%s %d enabled", module.ID, module.VersionCode)
+			if !module.Enabled {
+				state = fmt.Sprintf("%s %d disabled", module.ID, module.VersionCode)
+			}
+			if module.Remove {
+				state += " remove"
+			}
+			got = append(got, state)
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: modules = %q, want %q", step.name, got, step.want)
+		}
+	}
+}
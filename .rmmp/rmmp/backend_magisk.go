@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// magiskBackendFactory Magisk后端注册信息
+var magiskBackendFactory = RootBackendFactory{
+	Name:           "Magisk",
+	Marker:         "/data/adb/magisk",
+	Binary:         "/data/adb/magisk/magisk",
+	Probe:          probeMagisk,
+	MinVersionCode: 24000, // 24.0 起 --install-module 行为稳定
+	New: func(r *RMMD) RootBackend {
+		return &MagiskBackend{rmmd: r, binaryPath: "/data/adb/magisk/magisk"}
+	},
+}
+
+// probeMagisk 通过 magisk -v / -V 获取版本，并检查 magiskd 是否在运行
+// -v 输出形如 "27.0:MAGISK:R"，Kitsune (Delta) 和 Alpha 分支的版本名中带有分支名
+func probeMagisk(binary string) (RootVersion, error) {
+	output, err := exec.Command(binary, "-v").Output()
+	if err != nil {
+		return RootVersion{}, fmt.Errorf("执行 %s -v 失败: %v", binary, err)
+	}
+
+	raw := strings.TrimSpace(string(output))
+	version := RootVersion{Variant: "Magisk", Version: strings.Split(raw, ":")[0]}
+	lower := strings.ToLower(raw)
+	switch {
+	case strings.Contains(lower, "kitsune"), strings.Contains(lower, "delta"):
+		version.Variant = "Magisk Kitsune"
+	case strings.Contains(lower, "alpha"):
+		version.Variant = "Magisk Alpha"
+	}
+
+	if output, err := exec.Command(binary, "-V").Output(); err == nil {
+		version.VersionCode, _ = parseIntString(strings.TrimSpace(string(output)))
+	}
+	version.Active = processRunning("magiskd")
+	return version, nil
+}
+
+// MagiskBackend Magisk后端
+// 模块列表和启用/禁用/卸载直接读写 /data/adb/modules，安装调用 magisk --install-module
+type MagiskBackend struct {
+	rmmd       *RMMD
+	binaryPath string
+}
+
+// Name 返回Root方案名称
+func (b *MagiskBackend) Name() string {
+	return "Magisk"
+}
+
+// Version 返回Magisk版本
+func (b *MagiskBackend) Version() (string, error) {
+	output, err := exec.Command(b.binaryPath, "-v").Output()
+	if err != nil {
+		return "", fmt.Errorf("获取Magisk版本失败: %v", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ListModules 列出已安装的模块
+func (b *MagiskBackend) ListModules() ([]ModuleInfo, error) {
+	return b.rmmd.listMagiskModules()
+}
+
+// InstallModule 安装模块
+func (b *MagiskBackend) InstallModule(zipPath string) error {
+	if !b.rmmd.fileExists(b.binaryPath) {
+		return fmt.Errorf("二进制文件不存在: %s", b.binaryPath)
+	}
+
+	cmd := exec.Command(b.binaryPath, "--install-module", zipPath)
+	cmd.Stdout = msgOut
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// UninstallModule 创建 remove 标记
+func (b *MagiskBackend) UninstallModule(moduleID string) error {
+	return b.rmmd.writeMarker(b.markerPath(moduleID, "remove"), true)
+}
+
+// UndoUninstallModule 删除 remove 标记
+func (b *MagiskBackend) UndoUninstallModule(moduleID string) error {
+	return b.rmmd.writeMarker(b.markerPath(moduleID, "remove"), false)
+}
+
+// EnableModule 删除 disable 标记
+func (b *MagiskBackend) EnableModule(moduleID string) error {
+	return b.rmmd.writeMarker(b.markerPath(moduleID, "disable"), false)
+}
+
+// DisableModule 创建 disable 标记
+func (b *MagiskBackend) DisableModule(moduleID string) error {
+	return b.rmmd.writeMarker(b.markerPath(moduleID, "disable"), true)
+}
+
+// markerPath 获取模块标记文件路径
+func (b *MagiskBackend) markerPath(moduleID, marker string) string {
+	return filepath.Join(modulesDir, moduleID, marker)
+}
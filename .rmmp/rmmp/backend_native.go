@@ -0,0 +1,442 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	// 模块安装的暂存目录，安装脚本在这里执行
+	modulesUpdateDir = "/data/adb/modules_update"
+	// 设置了该值的 customize.sh 自行解压文件
+	skipUnzipLine = "SKIPUNZIP=1"
+	// 每次启动都会变化的内核启动ID，用于判断暂存的模块是否已经经过重启
+	bootIDPath = "/proc/sys/kernel/random/boot_id"
+)
+
+// nativeBackendFactory 内置安装器注册信息
+// 只在没有检测到其他Root环境时使用，也可以通过 --root-env Native 指定
+var nativeBackendFactory = RootBackendFactory{
+	Name:     "Native",
+	Marker:   modulesDir,
+	Fallback: true,
+	// sysroot 可以是空目录，便于在电脑上测试安装
+	Detect: func(r *RMMD) bool {
+		return sysroot != "" || r.dirExists(modulesDir)
+	},
+	New: func(r *RMMD) RootBackend {
+		return &NativeBackend{rmmd: r}
+	},
+}
+
+// NativeBackend 不依赖Root管理器的后端
+// 安装流程与 Magisk 的 install_module 一致，启用/禁用/卸载直接读写标记文件，
+// 在电脑上配合 --sysroot 可以测试模块的安装
+type NativeBackend struct {
+	rmmd *RMMD
+}
+
+// Name 返回Root方案名称
+func (b *NativeBackend) Name() string {
+	return "Native"
+}
+
+// Version 返回内置安装器版本
+func (b *NativeBackend) Version() (string, error) {
+	return "rmmp " + version, nil
+}
+
+// ListModules 扫描模块目录，还没有安装过模块时返回空列表
+func (b *NativeBackend) ListModules() ([]ModuleInfo, error) {
+	if !b.rmmd.dirExists(modulesDir) {
+		return []ModuleInfo{}, nil
+	}
+	return b.rmmd.listMagiskModules()
+}
+
+// InstallModule 使用内置安装器安装模块
+func (b *NativeBackend) InstallModule(zipPath string) error {
+	return b.rmmd.installModuleNative(zipPath)
+}
+
+// UninstallModule 创建 remove 标记
+func (b *NativeBackend) UninstallModule(moduleID string) error {
+	return b.rmmd.writeMarker(filepath.Join(modulesDir, moduleID, "remove"), true)
+}
+
+// UndoUninstallModule 删除 remove 标记
+func (b *NativeBackend) UndoUninstallModule(moduleID string) error {
+	return b.rmmd.writeMarker(filepath.Join(modulesDir, moduleID, "remove"), false)
+}
+
+// EnableModule 删除 disable 标记
+func (b *NativeBackend) EnableModule(moduleID string) error {
+	return b.rmmd.writeMarker(filepath.Join(modulesDir, moduleID, "disable"), false)
+}
+
+// DisableModule 创建 disable 标记
+func (b *NativeBackend) DisableModule(moduleID string) error {
+	return b.rmmd.writeMarker(filepath.Join(modulesDir, moduleID, "disable"), true)
+}
+
+// installModuleNative 解压模块到 modules_update/<id> 并执行 customize.sh
+// 与 Magisk 一致，运行中的系统上模块留在 modules_update 中，重启后才替换 modules/<id>；
+// sysroot 是离线的目录，没有正在使用的模块，直接合并到 modules/<id>
+func (r *RMMD) installModuleNative(zipPath string) error {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("打开模块文件失败: %v", err)
+	}
+	defer reader.Close()
+
+	var propFile *zip.File
+	for _, f := range reader.File {
+		if f.Name == "module.prop" {
+			propFile = f
+			break
+		}
+	}
+	if propFile == nil {
+		return fmt.Errorf("模块中缺少 module.prop")
+	}
+	props, err := r.readZipProperties(propFile)
+	if err != nil {
+		return err
+	}
+	moduleID := props["id"]
+	if !moduleIDPattern.MatchString(moduleID) {
+		return fmt.Errorf("无效的模块ID: %s", moduleID)
+	}
+
+	stageDir := hostPath(filepath.Join(modulesUpdateDir, moduleID))
+	if err := os.RemoveAll(stageDir); err != nil {
+		return fmt.Errorf("清理暂存目录失败: %v", err)
+	}
+	if err := os.MkdirAll(stageDir, 0755); err != nil {
+		return fmt.Errorf("创建暂存目录失败: %v", err)
+	}
+	installed := false
+	defer func() {
+		if !installed {
+			os.RemoveAll(stageDir)
+		}
+	}()
+
+	script := &moduleInstallScript{zipPath: zipPath, modPath: stageDir}
+	if err := script.extract(&reader.Reader); err != nil {
+		return err
+	}
+	if err := script.run(); err != nil {
+		return err
+	}
+
+	removeInstallOnlyFiles(stageDir)
+	if _, err := os.Stat(filepath.Join(stageDir, "module.prop")); err != nil {
+		return fmt.Errorf("安装脚本执行后缺少 module.prop")
+	}
+
+	moduleDir := hostPath(filepath.Join(modulesDir, moduleID))
+	if sysroot != "" {
+		err = moveModuleIntoPlace(stageDir, moduleDir)
+	} else {
+		err = stageModuleUpdate(stageDir, moduleDir, currentBootID())
+	}
+	if err != nil {
+		return err
+	}
+	installed = true
+	return nil
+}
+
+// stageModuleUpdate 像 Magisk 一样在 modules/<id> 中标记待更新，模块文件留在暂存目录
+// update 标记中记录本次启动的ID，applyStagedModules 据此只合并重启之前暂存的模块
+func stageModuleUpdate(stageDir, moduleDir, bootID string) error {
+	if _, err := os.Stat(filepath.Join(moduleDir, "disable")); err == nil {
+		if err := os.WriteFile(filepath.Join(stageDir, "disable"), nil, 0644); err != nil {
+			return fmt.Errorf("保留禁用状态失败: %v", err)
+		}
+	}
+
+	if err := os.MkdirAll(moduleDir, 0755); err != nil {
+		return fmt.Errorf("创建模块目录失败: %v", err)
+	}
+	if err := copyFile(filepath.Join(stageDir, "module.prop"), filepath.Join(moduleDir, "module.prop")); err != nil {
+		return fmt.Errorf("复制 module.prop 失败: %v", err)
+	}
+	os.Remove(filepath.Join(moduleDir, "remove"))
+	if err := os.WriteFile(filepath.Join(moduleDir, "update"), []byte(bootID), 0644); err != nil {
+		return fmt.Errorf("创建 update 标记失败: %v", err)
+	}
+
+	fmt.Fprintln(msgOut, "📦 模块已暂存到 modules_update，重启后生效")
+	return nil
+}
+
+// applyStagedModules 将重启之前暂存的模块合并到 modules/<id>
+// 代替 Root管理器开机时的合并，由开机启动的守护进程在使用内置安装器时调用
+func applyStagedModules(bootID string) error {
+	stagingDir := hostPath(modulesUpdateDir)
+	entries, err := os.ReadDir(stagingDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取暂存目录失败: %v", err)
+	}
+
+	for _, entry := range entries {
+		moduleID := entry.Name()
+		if !entry.IsDir() || !moduleIDPattern.MatchString(moduleID) {
+			continue
+		}
+
+		moduleDir := hostPath(filepath.Join(modulesDir, moduleID))
+		marker, err := os.ReadFile(filepath.Join(moduleDir, "update"))
+		if err == nil && bootID != "" && strings.TrimSpace(string(marker)) == bootID {
+			// 本次启动中安装的，等下次重启
+			continue
+		}
+
+		if err := moveModuleIntoPlace(filepath.Join(stagingDir, moduleID), moduleDir); err != nil {
+			return fmt.Errorf("合并模块 %s 失败: %v", moduleID, err)
+		}
+		fmt.Fprintf(msgOut, "✅ 已合并暂存的模块: %s\n", moduleID)
+	}
+	return nil
+}
+
+// currentBootID 返回本次启动的ID，读取失败时返回空
+func currentBootID() string {
+	data, err := os.ReadFile(bootIDPath)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// removeInstallOnlyFiles 与 Magisk 一致，删除只在安装时需要的文件
+func removeInstallOnlyFiles(modPath string) {
+	for _, name := range []string{"customize.sh", "README.md"} {
+		os.Remove(filepath.Join(modPath, name))
+	}
+	gitFiles, _ := filepath.Glob(filepath.Join(modPath, ".git*"))
+	for _, file := range gitFiles {
+		os.RemoveAll(file)
+	}
+}
+
+// moveModuleIntoPlace 用暂存目录替换已安装的模块，保留禁用状态
+func moveModuleIntoPlace(stageDir, moduleDir string) error {
+	if _, err := os.Stat(filepath.Join(moduleDir, "disable")); err == nil {
+		if err := os.WriteFile(filepath.Join(stageDir, "disable"), nil, 0644); err != nil {
+			return fmt.Errorf("保留禁用状态失败: %v", err)
+		}
+	}
+
+	if err := os.RemoveAll(moduleDir); err != nil {
+		return fmt.Errorf("删除旧模块失败: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(moduleDir), 0755); err != nil {
+		return fmt.Errorf("创建模块目录失败: %v", err)
+	}
+	if err := os.Rename(stageDir, moduleDir); err != nil {
+		return fmt.Errorf("移动模块失败: %v", err)
+	}
+	return nil
+}
+
+// moduleInstallScript 在本机路径 modPath 中解压模块并执行 customize.sh
+type moduleInstallScript struct {
+	zipPath   string
+	modPath   string
+	skipUnzip bool
+	// helpers 提供给 customize.sh 的 shell 函数，为空时使用 installHelpers()
+	helpers string
+	// tmpDir 脚本的 TMPDIR，为空时使用临时目录并在执行后删除
+	tmpDir string
+	// env 额外的环境变量，会覆盖同名变量
+	env []string
+	// wrapper 放在 sh 前面执行的命令，如 unshare
+	wrapper []string
+}
+
+// extract 解压模块文件
+// customize.sh 中声明 SKIPUNZIP=1 时只解压 module.prop 和 customize.sh，其余文件由脚本自行处理
+func (s *moduleInstallScript) extract(zr *zip.Reader) error {
+	for _, f := range zr.File {
+		if f.Name != "customize.sh" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("读取 customize.sh 失败: %v", err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("读取 customize.sh 失败: %v", err)
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			if strings.TrimSpace(line) == skipUnzipLine {
+				s.skipUnzip = true
+			}
+		}
+	}
+
+	for _, f := range zr.File {
+		if strings.HasPrefix(f.Name, "META-INF/") {
+			continue
+		}
+		if s.skipUnzip && f.Name != "module.prop" && f.Name != "customize.sh" {
+			continue
+		}
+		if err := extractZipEntry(f, s.modPath, f.Name); err != nil {
+			return fmt.Errorf("解压 %s 失败: %v", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// run 设置默认权限并在 sh 中执行 customize.sh
+func (s *moduleInstallScript) run() error {
+	tmpDir := s.tmpDir
+	if tmpDir == "" {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "rmmp-install-")
+		if err != nil {
+			return fmt.Errorf("创建临时目录失败: %v", err)
+		}
+		defer os.RemoveAll(tmpDir)
+	}
+
+	args := append(append([]string{}, s.wrapper...), "sh", "-c", s.shellScript())
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = tmpDir
+	cmd.Env = append(append(os.Environ(), s.environ(tmpDir)...), s.env...)
+	cmd.Stdout = msgOut
+	cmd.Stderr = os.Stderr
+	if len(s.wrapper) > 0 {
+		// 通过管道转发输出，脚本持有指向本机文件的写入描述符时无法重新挂载为只读
+		cmd.Stdout = struct{ io.Writer }{msgOut}
+		cmd.Stderr = struct{ io.Writer }{os.Stderr}
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("执行安装脚本失败: %v", err)
+	}
+	return nil
+}
+
+// environ 返回安装脚本可以使用的环境变量
+func (s *moduleInstallScript) environ(tmpDir string) []string {
+	arch, is64bit := moduleArch()
+	skipUnzip := "0"
+	if s.skipUnzip {
+		skipUnzip = "1"
+	}
+	return []string{
+		"MODPATH=" + s.modPath,
+		"ZIPFILE=" + s.zipPath,
+		"TMPDIR=" + tmpDir,
+		"NVBASE=" + hostPath(deviceAdbDir),
+		"BOOTMODE=true",
+		"ARCH=" + arch,
+		"IS64BIT=" + is64bit,
+		"API=" + androidAPILevel(),
+		"SKIPUNZIP=" + skipUnzip,
+		"RMMP=true",
+	}
+}
+
+// shellScript 生成安装脚本：定义函数、设置默认权限后执行 customize.sh
+func (s *moduleInstallScript) shellScript() string {
+	helpers := s.helpers
+	if helpers == "" {
+		helpers = installHelpers()
+	}
+	return helpers + `if [ "$SKIPUNZIP" != "1" ]; then
+  ui_print "- Extracting module files"
+  set_perm_recursive "$MODPATH" 0 0 0755 0644
+  set_perm_recursive "$MODPATH/system/bin" 0 2000 0755 0755
+  set_perm_recursive "$MODPATH/system/xbin" 0 2000 0755 0755
+  set_perm_recursive "$MODPATH/system/system_ext/bin" 0 2000 0755 0755
+  set_perm_recursive "$MODPATH/system/vendor" 0 2000 0755 0755 u:object_r:vendor_file:s0
+fi
+[ -f "$MODPATH/customize.sh" ] && . "$MODPATH/customize.sh"
+for TARGET in $REPLACE; do
+  case "$TARGET" in /*/../*|/*/..|/..*) abort "! 无效的 REPLACE 路径: $TARGET" ;; /*) ;; *) abort "! REPLACE 路径必须以 / 开头: $TARGET" ;; esac
+  ui_print "- Replace target: $TARGET"
+  mkdir -p "$MODPATH$TARGET" && touch "$MODPATH$TARGET/.replace" || abort "! 无法创建 $TARGET/.replace"
+done
+for TARGET in $REMOVE; do
+  case "$TARGET" in /*/../*|/*/..|/..*) abort "! 无效的 REMOVE 路径: $TARGET" ;; /*) ;; *) abort "! REMOVE 路径必须以 / 开头: $TARGET" ;; esac
+  ui_print "- Remove target: $TARGET"
+  rm -rf "$MODPATH$TARGET"
+  mkdir -p "$(dirname "$MODPATH$TARGET")" && mkwhiteout "$MODPATH$TARGET" || abort "! 无法为 $TARGET 创建删除标记 (mknod 需要root权限)"
+done
+exit 0
+`
+}
+
+// installHelpers 返回与 Magisk util_functions.sh 相同的安装函数
+func installHelpers() string {
+	var b strings.Builder
+	b.WriteString(`ui_print() { echo "$1"; }
+abort() { ui_print "$1"; exit 1; }
+set_perm() {
+  chown "$2:$3" "$1" || return 1
+  chmod "$4" "$1" || return 1
+  local CON="$5"
+  [ -z "$CON" ] && CON=u:object_r:system_file:s0
+  chcon "$CON" "$1" || return 1
+}
+set_perm_recursive() {
+  find "$1" -type d 2>/dev/null | while IFS= read -r dir; do
+    set_perm "$dir" "$2" "$3" "$4" "$6"
+  done
+  find "$1" \( -type f -o -type l \) 2>/dev/null | while IFS= read -r file; do
+    set_perm "$file" "$2" "$3" "$5" "$6"
+  done
+}
+mkwhiteout() { mknod "$1" c 0 0; }
+`)
+	// 非root时无法修改所有者，没有SELinux时无法设置上下文，忽略这些错误以便在电脑上测试
+	if os.Geteuid() != 0 {
+		b.WriteString("chown() { command chown \"$@\" 2>/dev/null || true; }\n")
+	}
+	if _, err := os.Stat("/sys/fs/selinux"); err != nil {
+		b.WriteString("chcon() { :; }\n")
+	}
+	return b.String()
+}
+
+// moduleArch 返回 Magisk 使用的 ARCH 和 IS64BIT
+func moduleArch() (string, string) {
+	switch runtime.GOARCH {
+	case "arm64":
+		return "arm64", "true"
+	case "arm":
+		return "arm", "false"
+	case "amd64":
+		return "x64", "true"
+	case "386":
+		return "x86", "false"
+	case "riscv64":
+		return "riscv64", "true"
+	}
+	return runtime.GOARCH, "false"
+}
+
+// androidAPILevel 通过 getprop 获取API级别，非Android环境返回 0
+func androidAPILevel() string {
+	output, err := exec.Command("getprop", "ro.build.version.sdk").Output()
+	if err != nil || strings.TrimSpace(string(output)) == "" {
+		return "0"
+	}
+	return strings.TrimSpace(string(output))
+}
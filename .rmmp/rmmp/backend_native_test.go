@@ -0,0 +1,270 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// withSysroot 将 sysroot 指向临时目录，测试结束后恢复
+func withSysroot(t *testing.T) string {
+	t.Helper()
+
+	old := sysroot
+	t.Cleanup(func() { sysroot = old })
+	sysroot = t.TempDir()
+	return sysroot
+}
+
+func TestInstallModuleNativeReplaceRemove(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		wantErr string
+		check   func(t *testing.T, moduleDir string)
+	}{
+		{
+			name:   "replace",
+			script: "REPLACE=\"/system/app/Foo /system/priv-app/Bar\"\n",
+			check: func(t *testing.T, moduleDir string) {
+				for _, dir := range []string{"system/app/Foo", "system/priv-app/Bar"} {
+					if _, err := os.Stat(filepath.Join(moduleDir, dir, ".replace")); err != nil {
+						t.Errorf("%s/.replace: %v", dir, err)
+					}
+				}
+			},
+		},
+		{
+			name:   "remove",
+			script: "REMOVE=\"/system/app/Foo\"\n",
+			check: func(t *testing.T, moduleDir string) {
+				info, err := os.Lstat(filepath.Join(moduleDir, "system/app/Foo"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				stat, ok := info.Sys().(*syscall.Stat_t)
+				if info.Mode()&os.ModeCharDevice == 0 || !ok || stat.Rdev != 0 {
+					t.Errorf("system/app/Foo mode = %v, want whiteout char device 0:0", info.Mode())
+				}
+			},
+		},
+		{
+			name:    "relative replace",
+			script:  "REPLACE=\"system/app/Foo\"\n",
+			wantErr: "执行安装脚本失败",
+		},
+		{
+			name:    "replace escaping module",
+			script:  "REPLACE=\"/system/../../../evil\"\n",
+			wantErr: "执行安装脚本失败",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := withSysroot(t)
+			zipPath := testModuleZip(t, "demo", 1, map[string]string{"customize.sh": tt.script})
+
+			err := (&RMMD{}).installModuleNative(zipPath)
+			if tt.name == "remove" && err != nil && os.Geteuid() != 0 {
+				// 没有root权限时 mknod 失败，必须明确拒绝而不是装出一个行为不同的模块
+				if !strings.Contains(err.Error(), "执行安装脚本失败") {
+					t.Fatalf("error = %v, want install script failure", err)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				if _, err := os.Stat(filepath.Join(root, "modules", "demo")); err == nil {
+					t.Error("module installed despite failed script")
+				}
+				if _, err := os.Lstat(filepath.Join(root, "evil")); err == nil {
+					t.Error("REPLACE created a file outside the module")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, filepath.Join(root, "modules", "demo"))
+		})
+	}
+}
+
+func TestStageAndApplyModuleUpdate(t *testing.T) {
+	root := withSysroot(t)
+	stageDir := filepath.Join(root, "modules_update", "demo")
+	moduleDir := filepath.Join(root, "modules", "demo")
+
+	for dir, files := range map[string]map[string]string{
+		stageDir:  {"module.prop": "id=demo\nversionCode=2\n", "new": ""},
+		moduleDir: {"module.prop": "id=demo\nversionCode=1\n", "old": "", "disable": "", "remove": ""},
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := stageModuleUpdate(stageDir, moduleDir, "boot-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重启之前：旧模块仍在使用，只更新 module.prop 和标记
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+	steps := []struct {
+		bootID string
+		want   map[string]bool
+	}{
+		{
+			bootID: "boot-1",
+			want: map[string]bool{
+				filepath.Join(moduleDir, "old"):     true,
+				filepath.Join(moduleDir, "new"):     false,
+				filepath.Join(moduleDir, "update"):  true,
+				filepath.Join(moduleDir, "remove"):  false,
+				filepath.Join(moduleDir, "disable"): true,
+				filepath.Join(stageDir, "disable"):  true,
+			},
+		},
+		{
+			bootID: "boot-2",
+			want: map[string]bool{
+				filepath.Join(moduleDir, "old"):     false,
+				filepath.Join(moduleDir, "new"):     true,
+				filepath.Join(moduleDir, "update"):  false,
+				filepath.Join(moduleDir, "disable"): true,
+				stageDir:                            false,
+			},
+		},
+	}
+
+	for _, step := range steps {
+		if err := applyStagedModules(step.bootID); err != nil {
+			t.Fatal(err)
+		}
+		for path, want := range step.want {
+			if got := exists(path); got != want {
+				rel, _ := filepath.Rel(root, path)
+				t.Errorf("%s: %s exists = %v, want %v", step.bootID, rel, got, want)
+			}
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(moduleDir, "module.prop"))
+	if err != nil || !strings.Contains(string(data), "versionCode=2") {
+		t.Errorf("module.prop = %q, %v", data, err)
+	}
+}
+
+func TestInstallModuleNative(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		// existing 安装前已存在的 modules/demo 中的文件
+		existing map[string]string
+		wantErr  string
+		// want 安装后 modules/demo 中应存在 (true) 或不存在 (false) 的文件
+		want map[string]bool
+	}{
+		{
+			name:  "default extraction",
+			files: map[string]string{"system/bin/foo": "#!/bin/sh\n", "service.sh": "true\n"},
+			want:  map[string]bool{"module.prop": true, "system/bin/foo": true, "service.sh": true},
+		},
+		{
+			name: "install-only files removed",
+			files: map[string]string{
+				"README.md":                   "# demo",
+				".gitignore":                  "*.zip",
+				".github/workflows/build.yml": "",
+				"META-INF/com/google/android/update-binary": "#!/sbin/sh\n",
+			},
+			want: map[string]bool{
+				"module.prop": true, "customize.sh": false, "README.md": false,
+				".gitignore": false, ".github": false, "META-INF": false,
+			},
+		},
+		{
+			name: "skip unzip",
+			files: map[string]string{
+				"customize.sh":   "SKIPUNZIP=1\ntouch \"$MODPATH/by-script\"\n",
+				"system/bin/foo": "",
+				"extra":          "",
+			},
+			want: map[string]bool{"module.prop": true, "by-script": true, "system": false, "extra": false},
+		},
+		{
+			name:     "disable kept on reinstall",
+			existing: map[string]string{"module.prop": "id=demo\n", "disable": "", "stale": ""},
+			want:     map[string]bool{"disable": true, "stale": false, "module.prop": true},
+		},
+		{
+			name:     "enabled stays enabled",
+			existing: map[string]string{"module.prop": "id=demo\n"},
+			want:     map[string]bool{"disable": false},
+		},
+		{
+			name:    "abort",
+			files:   map[string]string{"customize.sh": "abort '! unsupported device'\n"},
+			wantErr: "执行安装脚本失败",
+		},
+		{
+			name:    "script removes module.prop",
+			files:   map[string]string{"customize.sh": "rm -f \"$MODPATH/module.prop\"\n"},
+			wantErr: "缺少 module.prop",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := withSysroot(t)
+			moduleDir := filepath.Join(root, "modules", "demo")
+			if tt.existing != nil {
+				if err := os.MkdirAll(moduleDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				for name, content := range tt.existing {
+					if err := os.WriteFile(filepath.Join(moduleDir, name), []byte(content), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			err := (&RMMD{}).installModuleNative(testModuleZip(t, "demo", 1, tt.files))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				if _, err := os.Stat(filepath.Join(root, "modules_update", "demo")); err == nil {
+					t.Error("staging directory left behind after failure")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for name, want := range tt.want {
+				_, err := os.Lstat(filepath.Join(moduleDir, name))
+				if got := err == nil; got != want {
+					t.Errorf("%s exists = %v, want %v", name, got, want)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(root, "modules_update", "demo")); err == nil {
+				t.Error("sysroot install left the module in modules_update")
+			}
+		})
+	}
+}
@@ -0,0 +1,734 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// 备份文件格式版本
+	backupFormatVersion = 1
+	// 备份清单文件名
+	backupManifestName = "manifest.json"
+)
+
+// 模块目录中的状态标记，导出为安装包时需要去掉
+var moduleStateMarkers = map[string]bool{
+	"disable": true,
+	"remove":  true,
+	"update":  true,
+}
+
+// 不能作为模块配置目录备份的 /data/adb 子目录
+var reservedAdbDirs = map[string]bool{
+	"magisk":         true,
+	"ksu":            true,
+	"ap":             true,
+	"modules":        true,
+	"modules_update": true,
+	"post-fs-data.d": true,
+	"service.d":      true,
+	"rmmp":           true,
+}
+
+// Magisk 标准的 update-binary，调用设备上的 util_functions.sh 完成安装
+const moduleUpdateBinary = `#!/sbin/sh
+
+#################
+# Initialization
+#################
+
+umask 022
+
+# echo before loading util_functions
+ui_print() { echo "$1"; }
+
+require_new_magisk() {
+  ui_print "*******************************"
+  ui_print " Please install Magisk v20.4+! "
+  ui_print "*******************************"
+  exit 1
+}
+
+#########################
+# Load util_functions.sh
+#########################
+
+OUTFD=$2
+ZIPFILE=$3
+
+mount /data 2>/dev/null
+
+[ -f /data/adb/magisk/util_functions.sh ] || require_new_magisk
+. /data/adb/magisk/util_functions.sh
+[ $MAGISK_VER_CODE -lt 20400 ] && require_new_magisk
+
+install_module
+exit 0
+`
+
+// BackupManifest 备份文件的清单
+type BackupManifest struct {
+	FormatVersion int            `json:"formatVersion"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	RootEnv       string         `json:"rootEnv"`
+	Modules       []BackupModule `json:"modules"`
+}
+
+// BackupModule 备份中的单个模块
+type BackupModule struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	VersionCode int    `json:"versionCode"`
+	Enabled     bool   `json:"enabled"`
+	Files       int    `json:"files"`
+	// DataDir 一并备份的模块配置目录（设备路径），如 /data/adb/<id>
+	DataDir string `json:"dataDir,omitempty"`
+}
+
+// BackupModules 将模块目录、启用状态和配置目录打包到一个备份文件
+// ids 为空时备份所有模块
+func (r *RMMD) BackupModules(ids []string, archivePath string) (*BackupManifest, error) {
+	modules, err := r.ListModules()
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := selectModules(modules, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("创建备份文件失败: %v", err)
+	}
+	defer file.Close()
+
+	w := zip.NewWriter(file)
+	manifest := &BackupManifest{
+		FormatVersion: backupFormatVersion,
+		CreatedAt:     time.Now(),
+		RootEnv:       r.getRootEnvName(),
+	}
+
+	for _, module := range selected {
+		fmt.Fprintf(msgOut, "📦 正在备份 %s...\n", module.ID)
+
+		entry := BackupModule{
+			ID:          module.ID,
+			Name:        module.Name,
+			Version:     module.Version,
+			VersionCode: module.VersionCode,
+			Enabled:     module.Enabled,
+		}
+
+		moduleDir := hostPath(filepath.Join(modulesDir, module.DirID))
+		entry.Files, err = addDirToZip(w, moduleDir, "modules/"+module.ID, nil)
+		if err != nil {
+			return nil, fmt.Errorf("备份模块 %s 失败: %v", module.ID, err)
+		}
+
+		if dataDir := moduleDataDir(module.ID); r.dirExists(dataDir) {
+			if _, err := addDirToZip(w, hostPath(dataDir), "data/"+module.ID, nil); err != nil {
+				return nil, fmt.Errorf("备份模块 %s 的配置目录失败: %v", module.ID, err)
+			}
+			entry.DataDir = dataDir
+		}
+
+		manifest.Modules = append(manifest.Modules, entry)
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化备份清单失败: %v", err)
+	}
+	mw, err := w.Create(backupManifestName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := mw.Write(manifestData); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("写入备份文件失败: %v", err)
+	}
+	return manifest, nil
+}
+
+// RestoreModules 从备份文件重新安装模块，并恢复配置目录和启用状态
+func (r *RMMD) RestoreModules(archivePath string) (*BackupManifest, error) {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开备份文件: %v", err)
+	}
+	defer reader.Close()
+
+	manifest, err := readBackupManifest(&reader.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "rmmp-restore-")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, module := range manifest.Modules {
+		fmt.Fprintf(msgOut, "\n♻️  正在恢复 %s (%s)...\n", module.ID, module.Version)
+
+		zipPath := filepath.Join(tmpDir, module.ID+".zip")
+		if err := repackBackupModule(&reader.Reader, module.ID, zipPath, !module.Enabled); err != nil {
+			return nil, fmt.Errorf("重新打包模块 %s 失败: %v", module.ID, err)
+		}
+
+		// 恢复时保持与备份一致，不因文件冲突中断
+		if err := r.InstallModule(zipPath, InstallOptions{ConflictPolicy: ConflictContinue}); err != nil {
+			return nil, fmt.Errorf("安装模块 %s 失败: %v", module.ID, err)
+		}
+
+		if dataDir := moduleDataDir(module.ID); module.DataDir != "" && dataDir != "" {
+			if err := extractZipDir(&reader.Reader, "data/"+module.ID, hostPath(dataDir)); err != nil {
+				return nil, fmt.Errorf("恢复模块 %s 的配置目录失败: %v", module.ID, err)
+			}
+			fmt.Fprintf(msgOut, "📁 已恢复配置目录: %s\n", dataDir)
+		}
+
+		// 安装包中的 disable 标记随模块一起暂存，重启合并后仍然有效；
+		// 这里再标记当前列出的模块，使重启前的状态也一致
+		if !module.Enabled {
+			if _, err := r.DisableModule(module.ID); err != nil {
+				fmt.Fprintf(msgOut, "⚠️  恢复模块 %s 的禁用状态失败: %v\n", module.ID, err)
+			}
+		}
+	}
+
+	return manifest, nil
+}
+
+// ExportModule 将已安装的模块重新打包为可安装的zip
+func (r *RMMD) ExportModule(moduleID, zipPath string) error {
+	module, err := r.findModule(moduleID)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(zipPath)
+	if err != nil {
+		return fmt.Errorf("创建文件失败: %v", err)
+	}
+	defer file.Close()
+
+	w := zip.NewWriter(file)
+	moduleDir := hostPath(filepath.Join(modulesDir, module.DirID))
+	if _, err := addDirToZip(w, moduleDir, "", isModuleStateMarker); err != nil {
+		return fmt.Errorf("打包模块失败: %v", err)
+	}
+	if err := writeModuleInstaller(w); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
+
+// selectModules 按ID挑选模块，ids 为空时返回全部
+func selectModules(modules []ModuleInfo, ids []string) ([]ModuleInfo, error) {
+	if len(ids) == 0 {
+		return modules, nil
+	}
+
+	byID := make(map[string]ModuleInfo)
+	for _, module := range modules {
+		byID[module.ID] = module
+	}
+
+	selected := make([]ModuleInfo, 0, len(ids))
+	for _, id := range ids {
+		module, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("模块未安装: %s", id)
+		}
+		selected = append(selected, module)
+	}
+	return selected, nil
+}
+
+// moduleDataDir 模块约定使用的配置目录 /data/adb/<id>
+// 与Root管理器自身目录同名的模块没有独立配置目录
+func moduleDataDir(moduleID string) string {
+	if reservedAdbDirs[moduleID] {
+		return ""
+	}
+	return filepath.Join(deviceAdbDir, moduleID)
+}
+
+// isModuleStateMarker 判断模块目录中的相对路径是否为状态标记
+func isModuleStateMarker(rel string) bool {
+	return moduleStateMarkers[rel]
+}
+
+// addDirToZip 将本地目录写入zip，条目名以 prefix 开头，返回写入的文件数
+// skip 返回 true 的相对路径会被跳过；符号链接按链接本身保存
+func addDirToZip(w *zip.Writer, dir, prefix string, skip func(rel string) bool) (int, error) {
+	count := 0
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if skip != nil && skip(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = strings.TrimPrefix(prefix+"/"+rel, "/")
+		if d.IsDir() {
+			header.Name += "/"
+			_, err = w.CreateHeader(header)
+			return err
+		}
+		header.Method = zip.Deflate
+
+		fw, err := w.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		count++
+
+		if info.Mode()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(fw, target)
+			return err
+		}
+
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(fw, src)
+		return err
+	})
+	return count, err
+}
+
+// writeModuleInstaller 写入标准的 META-INF 安装脚本
+func writeModuleInstaller(w *zip.Writer) error {
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"META-INF/com/google/android/update-binary", moduleUpdateBinary},
+		{"META-INF/com/google/android/updater-script", "#MAGISK\n"},
+	}
+
+	for _, file := range files {
+		header := &zip.FileHeader{Name: file.name, Method: zip.Deflate}
+		header.SetMode(0755)
+		fw, err := w.CreateHeader(header)
+		if err != nil {
+			return fmt.Errorf("写入 %s 失败: %v", file.name, err)
+		}
+		if _, err := io.WriteString(fw, file.content); err != nil {
+			return fmt.Errorf("写入 %s 失败: %v", file.name, err)
+		}
+	}
+	return nil
+}
+
+// readBackupManifest 读取备份清单
+func readBackupManifest(zr *zip.Reader) (*BackupManifest, error) {
+	rc, err := zr.Open(backupManifestName)
+	if err != nil {
+		return nil, fmt.Errorf("备份文件中没有 %s: %v", backupManifestName, err)
+	}
+	defer rc.Close()
+
+	var manifest BackupManifest
+	if err := json.NewDecoder(rc).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("解析备份清单失败: %v", err)
+	}
+	if manifest.FormatVersion > backupFormatVersion {
+		return nil, fmt.Errorf("不支持的备份格式版本: %d", manifest.FormatVersion)
+	}
+
+	for _, module := range manifest.Modules {
+		if !moduleIDPattern.MatchString(module.ID) {
+			return nil, fmt.Errorf("备份清单中的模块ID无效: %s", module.ID)
+		}
+	}
+	return &manifest, nil
+}
+
+// repackBackupModule 将备份中的模块目录重新打包为可安装的zip
+// disabled 为 true 时在包中放入 disable 标记，Root管理器会把它和模块文件一起暂存到 modules_update
+func repackBackupModule(zr *zip.Reader, moduleID, zipPath string, disabled bool) error {
+	file, err := os.Create(zipPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := zip.NewWriter(file)
+	prefix := "modules/" + moduleID + "/"
+	for _, f := range zr.File {
+		if !strings.HasPrefix(f.Name, prefix) || f.Name == prefix {
+			continue
+		}
+
+		rel := strings.TrimPrefix(f.Name, prefix)
+		if isModuleStateMarker(rel) {
+			continue
+		}
+
+		header := f.FileHeader
+		header.Name = rel
+		if err := copyZipEntry(w, f, &header); err != nil {
+			return err
+		}
+	}
+
+	if disabled {
+		header := &zip.FileHeader{Name: "disable", Method: zip.Deflate}
+		header.SetMode(0644)
+		if _, err := w.CreateHeader(header); err != nil {
+			return err
+		}
+	}
+
+	if err := writeModuleInstaller(w); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// copyZipEntry 以新的文件头复制zip条目
+func copyZipEntry(w *zip.Writer, f *zip.File, header *zip.FileHeader) error {
+	fw, err := w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	if f.FileInfo().IsDir() {
+		return nil
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	_, err = io.Copy(fw, rc)
+	return err
+}
+
+// extractZipDir 将zip中 prefix 目录下的内容解压到本地目录
+func extractZipDir(zr *zip.Reader, prefix, dst string) error {
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+
+	for _, f := range zr.File {
+		if !strings.HasPrefix(f.Name, prefix) || f.Name == prefix {
+			continue
+		}
+		if err := extractZipEntry(f, dst, strings.TrimPrefix(f.Name, prefix)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractZipEntry 将zip条目解压到 dst 下的 name，保留权限和符号链接
+// 不会经过已有的符号链接写入，符号链接也不能指向 dst 之外，避免条目逃逸出解压目录
+func extractZipEntry(f *zip.File, dst, name string) error {
+	if isUnsafeZipPath(name) {
+		return fmt.Errorf("包含不安全的路径: %s", name)
+	}
+	rel := filepath.Clean(filepath.FromSlash(name))
+	target := filepath.Join(dst, rel)
+
+	if f.FileInfo().IsDir() {
+		return mkdirNoFollow(dst, rel)
+	}
+	if err := mkdirNoFollow(dst, filepath.Dir(rel)); err != nil {
+		return err
+	}
+	if !withinDir(dst, filepath.Dir(target)) {
+		return fmt.Errorf("%s 的父目录位于解压目录之外", name)
+	}
+
+	// 已存在的符号链接先删除，否则会写入链接指向的文件
+	if info, err := os.Lstat(target); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%s 已存在且是目录", name)
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if err := os.Remove(target); err != nil {
+				return err
+			}
+		}
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %v", name, err)
+	}
+	defer rc.Close()
+
+	mode := f.Mode()
+	if mode&fs.ModeSymlink != 0 {
+		linkTarget, err := io.ReadAll(rc)
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %v", name, err)
+		}
+		link := filepath.FromSlash(string(linkTarget))
+		if filepath.IsAbs(link) || !withinDir(dst, filepath.Join(filepath.Dir(target), link)) {
+			return fmt.Errorf("符号链接 %s 指向解压目录之外: %s", name, linkTarget)
+		}
+		os.Remove(target)
+		return os.Symlink(link, target)
+	}
+
+	perm := mode.Perm()
+	if perm == 0 {
+		perm = 0644
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, rc)
+	return err
+}
+
+// mkdirNoFollow 逐级创建 dst 下的目录 rel，遇到符号链接或文件时报错
+// os.MkdirAll 会跟随符号链接，可能在解压目录之外创建目录
+func mkdirNoFollow(dst, rel string) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+
+	current := dst
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		switch {
+		case os.IsNotExist(err):
+			if err := os.Mkdir(current, 0755); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case info.Mode()&fs.ModeSymlink != 0:
+			return fmt.Errorf("路径经过符号链接: %s", current)
+		case !info.IsDir():
+			return fmt.Errorf("不是目录: %s", current)
+		}
+	}
+	return nil
+}
+
+// withinDir 判断 path 解析符号链接后是否位于 dir 之内
+// path 不存在时逐级向上解析已存在的部分
+func withinDir(dir, path string) bool {
+	base, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		base = filepath.Clean(dir)
+	}
+
+	resolved := filepath.Clean(path)
+	var rest []string
+	for {
+		if real, err := filepath.EvalSymlinks(resolved); err == nil {
+			resolved = filepath.Join(append([]string{real}, rest...)...)
+			break
+		}
+		parent := filepath.Dir(resolved)
+		if parent == resolved {
+			break
+		}
+		rest = append([]string{filepath.Base(resolved)}, rest...)
+		resolved = parent
+	}
+
+	rel, err := filepath.Rel(base, resolved)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// printBackupManifest 打印备份清单
+func printBackupManifest(title string, manifest *BackupManifest) {
+	fmt.Fprintf(msgOut, "%s (共 %d 个模块):\n", title, len(manifest.Modules))
+	for _, module := range manifest.Modules {
+		state := "🟢"
+		if !module.Enabled {
+			state = "🔴"
+		}
+		line := fmt.Sprintf("   %s %s %s (%d 个文件)", state, module.ID, module.Version, module.Files)
+		if module.DataDir != "" {
+			line += " + " + module.DataDir
+		}
+		fmt.Fprintln(msgOut, line)
+	}
+}
+
+// parseTargetFlag 从参数中取出 --to <路径>，返回剩余参数
+func parseTargetFlag(args []string) (string, []string, error) {
+	var target string
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if name != "--to" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return "", nil, fmt.Errorf("--to 需要一个参数")
+			}
+			i++
+			value = args[i]
+		}
+		target = value
+	}
+	return target, rest, nil
+}
+
+// handleBackupCommand 处理 module backup 命令
+func handleBackupCommand(args []string) {
+	target, rest, err := parseTargetFlag(args)
+	if err != nil {
+		fmt.Fprintf(msgOut, "错误: %v\n", err)
+		return
+	}
+
+	all := false
+	var ids []string
+	for _, arg := range rest {
+		if arg == "--all" || arg == "-a" {
+			all = true
+		} else {
+			ids = append(ids, arg)
+		}
+	}
+	if !all && len(ids) == 0 {
+		fmt.Fprintln(msgOut, "错误: 请指定要备份的模块ID，或使用 --all 备份所有模块")
+		fmt.Fprintln(msgOut, "用法: rmmp module backup <模块ID>... | --all [--to <备份文件>]")
+		return
+	}
+	if all {
+		ids = nil
+	}
+
+	if target == "" {
+		target = fmt.Sprintf("rmmp-backup-%s.zip", time.Now().Format("20060102-150405"))
+	}
+
+	rmmd := NewRMMD()
+	manifest, err := rmmd.BackupModules(ids, target)
+	if err != nil {
+		os.Remove(target)
+		fmt.Fprintf(msgOut, "❌ 备份失败: %v\n", err)
+		return
+	}
+
+	if machineOutput() {
+		if err := printData(manifest); err != nil {
+			fmt.Fprintf(msgOut, "❌ %v\n", err)
+		}
+		return
+	}
+	printBackupManifest("✅ 备份完成", manifest)
+	fmt.Fprintf(msgOut, "📁 备份文件: %s\n", target)
+}
+
+// handleRestoreCommand 处理 module restore 命令
+func handleRestoreCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintln(msgOut, "错误: 请指定备份文件")
+		fmt.Fprintln(msgOut, "用法: rmmp module restore <备份文件>")
+		return
+	}
+
+	rmmd := NewRMMD()
+	manifest, err := rmmd.RestoreModules(args[0])
+	if err != nil {
+		fmt.Fprintf(msgOut, "❌ 恢复失败: %v\n", err)
+		return
+	}
+
+	if machineOutput() {
+		if err := printData(manifest); err != nil {
+			fmt.Fprintf(msgOut, "❌ %v\n", err)
+		}
+		return
+	}
+	fmt.Fprintln(msgOut)
+	printBackupManifest("✅ 恢复完成", manifest)
+	fmt.Fprintln(msgOut, "🔄 需要重启设备后生效")
+}
+
+// handleExportCommand 处理 module export 命令
+func handleExportCommand(args []string) {
+	target, rest, err := parseTargetFlag(args)
+	if err != nil {
+		fmt.Fprintf(msgOut, "错误: %v\n", err)
+		return
+	}
+	if len(rest) < 1 {
+		fmt.Fprintln(msgOut, "错误: 请指定模块ID")
+		fmt.Fprintln(msgOut, "用法: rmmp module export <模块ID> [--to <zip文件>]")
+		return
+	}
+
+	moduleID := rest[0]
+	rmmd := NewRMMD()
+	if target == "" {
+		module, err := rmmd.findModule(moduleID)
+		if err != nil {
+			fmt.Fprintf(msgOut, "❌ 导出失败: %v\n", err)
+			return
+		}
+		target = fmt.Sprintf("%s-%s.zip", module.ID, strings.ReplaceAll(module.Version, "/", "_"))
+	}
+
+	if err := rmmd.ExportModule(moduleID, target); err != nil {
+		os.Remove(target)
+		fmt.Fprintf(msgOut, "❌ 导出失败: %v\n", err)
+		return
+	}
+
+	fmt.Fprintf(msgOut, "✅ 已导出模块 %s: %s\n", moduleID, target)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractZipEntry(t *testing.T) {
+	tests := []struct {
+		name string
+		// files 按文件名顺序解压，"->" 开头的内容为符号链接
+		files map[string]string
+		// setup 在解压之前准备解压目录，outside 是解压目录之外的目录
+		setup   func(t *testing.T, dst, outside string)
+		wantErr bool
+		// want 解压后应存在的普通文件及其内容
+		want map[string]string
+	}{
+		{
+			name:  "regular files",
+			files: map[string]string{"module.prop": "id=demo", "system/": "", "system/bin/foo": "bin"},
+			want:  map[string]string{"module.prop": "id=demo", "system/bin/foo": "bin"},
+		},
+		{
+			name:    "parent traversal",
+			files:   map[string]string{"../evil": "x"},
+			wantErr: true,
+		},
+		{
+			name:    "nested traversal",
+			files:   map[string]string{"system/../../evil": "x"},
+			wantErr: true,
+		},
+		{
+			name:    "absolute path",
+			files:   map[string]string{"/evil": "x"},
+			wantErr: true,
+		},
+		{
+			name:    "backslash traversal",
+			files:   map[string]string{"..\\evil": "x"},
+			wantErr: true,
+		},
+		{
+			name:    "absolute symlink",
+			files:   map[string]string{"link": "->/etc"},
+			wantErr: true,
+		},
+		{
+			name:    "relative symlink escaping",
+			files:   map[string]string{"system/link": "->../../outside"},
+			wantErr: true,
+		},
+		{
+			name:  "symlink inside destination",
+			files: map[string]string{"system/bin/foo": "bin", "system/link": "->bin/foo"},
+			want:  map[string]string{"system/bin/foo": "bin", "system/link": "bin"},
+		},
+		{
+			name:    "write through symlinked directory entry",
+			files:   map[string]string{"a": "->.", "a/evil": "x"},
+			wantErr: true,
+		},
+		{
+			name:  "existing symlinked parent",
+			files: map[string]string{"sub/evil": "x"},
+			setup: func(t *testing.T, dst, outside string) {
+				if err := os.Symlink(outside, filepath.Join(dst, "sub")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name:  "existing symlinked file is replaced",
+			files: map[string]string{"victim": "new"},
+			setup: func(t *testing.T, dst, outside string) {
+				if err := os.WriteFile(filepath.Join(outside, "victim"), []byte("old"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(filepath.Join(outside, "victim"), filepath.Join(dst, "victim")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: map[string]string{"victim": "new"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dst := filepath.Join(root, "dst")
+			outside := filepath.Join(root, "outside")
+			for _, dir := range []string{dst, outside} {
+				if err := os.Mkdir(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.setup != nil {
+				tt.setup(t, dst, outside)
+			}
+			before := listDir(t, outside)
+
+			reader, err := zip.OpenReader(writeTestZip(t, tt.files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+
+			err = nil
+			for _, f := range reader.File {
+				if err = extractZipEntry(f, dst, f.Name); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			for name, content := range tt.want {
+				data, err := os.ReadFile(filepath.Join(dst, filepath.FromSlash(name)))
+				if err != nil {
+					t.Errorf("%s: %v", name, err)
+				} else if string(data) != content {
+					t.Errorf("%s = %q, want %q", name, data, content)
+				}
+			}
+
+			// 无论成功与否，解压目录之外的内容都不能改变
+			after := listDir(t, outside)
+			if len(after) != len(before) {
+				t.Errorf("outside changed: %v → %v", before, after)
+			}
+			for name, content := range before {
+				if after[name] != content {
+					t.Errorf("outside/%s changed: %q → %q", name, content, after[name])
+				}
+			}
+			if _, err := os.Lstat(filepath.Join(root, "evil")); err == nil {
+				t.Error("evil written next to the destination")
+			}
+		})
+	}
+}
+
+// listDir 读取目录中所有普通文件的内容
+func listDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	files := map[string]string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[rel] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestWithinDir(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "dir")
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(root, filepath.Join(dir, "up")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("sub", filepath.Join(dir, "down")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{dir, true},
+		{filepath.Join(dir, "sub"), true},
+		{filepath.Join(dir, "sub", "missing", "file"), true},
+		{filepath.Join(dir, "down", "file"), true},
+		{filepath.Join(dir, "up"), false},
+		{filepath.Join(dir, "up", "file"), false},
+		{filepath.Join(dir, ".."), false},
+		{filepath.Join(root, "dir2"), false},
+	}
+
+	for _, tt := range tests {
+		if got := withinDir(dir, tt.path); got != tt.want {
+			t.Errorf("withinDir(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRestoreModulesKeepsDisabledState(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	manifest := `{"formatVersion":1,"modules":[` +
+		`{"id":"on","version":"v1","versionCode":1,"enabled":true},` +
+		`{"id":"off","version":"v1","versionCode":1,"enabled":false}]}`
+	files := map[string]string{backupManifestName: manifest}
+	for _, id := range []string{"on", "off"} {
+		files["modules/"+id+"/module.prop"] = "id=" + id + "\nname=" + id + "\nversion=v1\nversionCode=1\n"
+		files["modules/"+id+"/service.sh"] = "true\n"
+		// 备份中的状态标记不能原样带进安装包
+		files["modules/"+id+"/remove"] = ""
+	}
+	archive := writeTestZip(t, files)
+
+	r := NewRMMDWithBackend(NewFakeBackend())
+	if _, err := r.RestoreModules(archive); err != nil {
+		t.Fatal(err)
+	}
+
+	modules, err := r.ListModules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, module := range modules {
+		if want := module.ID == "on"; module.Enabled != want {
+			t.Errorf("%s enabled = %v, want %v", module.ID, module.Enabled, want)
+		}
+	}
+
+	// 暂存到 modules_update 的安装包本身要带有 disable 标记
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	for _, tt := range []struct {
+		id       string
+		disabled bool
+	}{{"on", false}, {"off", true}} {
+		zipPath := filepath.Join(t.TempDir(), tt.id+".zip")
+		if err := repackBackupModule(&reader.Reader, tt.id, zipPath, tt.disabled); err != nil {
+			t.Fatal(err)
+		}
+		packed, err := zip.OpenReader(zipPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries := map[string]bool{}
+		for _, f := range packed.File {
+			entries[f.Name] = true
+		}
+		packed.Close()
+
+		if entries["disable"] != tt.disabled {
+			t.Errorf("%s: disable in zip = %v, want %v", tt.id, entries["disable"], tt.disabled)
+		}
+		if entries["remove"] || !entries["service.sh"] || !entries["module.prop"] {
+			t.Errorf("%s: unexpected entries %v", tt.id, entries)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// InstallResult 批量安装中单个模块的结果
+type InstallResult struct {
+	Source  string `json:"source"`
+	ID      string `json:"id,omitempty"`
+	Version string `json:"version,omitempty"`
+	Status  string `json:"status"` // installed, failed, invalid, skipped
+	Reason  string `json:"reason,omitempty"`
+}
+
+// expandInstallArgs 展开参数中的通配符，没有匹配的通配符视为错误
+func expandInstallArgs(args []string) ([]string, error) {
+	var sources []string
+	for _, arg := range args {
+		if arg == "-" || strings.Contains(arg, "://") || !strings.ContainsAny(arg, "*?[") {
+			sources = append(sources, arg)
+			continue
+		}
+
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("无效的通配符 %s: %v", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("没有文件匹配 %s", arg)
+		}
+		sources = append(sources, matches...)
+	}
+	return sources, nil
+}
+
+// InstallModules 批量安装模块
+// 先校验全部模块，任何一个校验失败时都不安装，除非 keepGoing 为 true；
+// 安装过程中出错时同样停止安装剩余的模块
+func (r *RMMD) InstallModules(sources []string, opts InstallOptions, keepGoing bool) []InstallResult {
+	results := make([]InstallResult, len(sources))
+	zipPaths := make([]string, len(sources))
+	sourceOpts := make([]InstallOptions, len(sources))
+	seen := make(map[string]string)
+	invalid := 0
+
+	fmt.Fprintf(msgOut, "🔎 正在校验 %d 个模块...\n", len(sources))
+	for i, source := range sources {
+		results[i] = InstallResult{Source: source}
+		sourceOpts[i] = opts
+		sourceOpts[i].Origin = source
+
+		zipPath, cleanup, err := resolveInstallSource(source, &sourceOpts[i])
+		if err == nil {
+			defer cleanup()
+			var zipInfo *ModuleZipInfo
+			zipInfo, err = r.ValidateModuleZip(zipPath)
+			if err == nil {
+				results[i].ID = zipInfo.ID
+				results[i].Version = zipInfo.Props["version"]
+				if other, ok := seen[zipInfo.ID]; ok {
+					err = fmt.Errorf("与 %s 是同一个模块", other)
+				}
+				seen[zipInfo.ID] = source
+			}
+		}
+
+		if err != nil {
+			results[i].Status = "invalid"
+			results[i].Reason = err.Error()
+			invalid++
+			fmt.Fprintf(msgOut, "❌ %s: %v\n", source, err)
+			continue
+		}
+		zipPaths[i] = zipPath
+		fmt.Fprintf(msgOut, "✅ %s: %s (%s)\n", source, results[i].ID, results[i].Version)
+	}
+
+	if invalid > 0 && !keepGoing {
+		fmt.Fprintf(msgOut, "⛔ %d 个模块校验失败，未安装任何模块 (使用 --keep-going 安装其余模块)\n", invalid)
+		for i := range results {
+			if results[i].Status == "" {
+				results[i].Status = "skipped"
+				results[i].Reason = "其他模块校验失败"
+			}
+		}
+		return results
+	}
+
+	stopped := false
+	for i := range results {
+		if results[i].Status != "" {
+			continue
+		}
+		if stopped {
+			results[i].Status = "skipped"
+			results[i].Reason = "之前的模块安装失败"
+			continue
+		}
+
+		fmt.Fprintf(msgOut, "\n📦 [%d/%d] 正在安装 %s...\n", i+1, len(results), results[i].ID)
+		if err := r.InstallModuleWithDeps(zipPaths[i], sourceOpts[i]); err != nil {
+			results[i].Status = "failed"
+			results[i].Reason = err.Error()
+			fmt.Fprintf(msgOut, "❌ 模块安装失败: %v\n", err)
+			stopped = !keepGoing
+			continue
+		}
+		results[i].Status = "installed"
+	}
+
+	return results
+}
+
+// printInstallResults 打印批量安装结果表
+func printInstallResults(results []InstallResult) {
+	icons := map[string]string{
+		"installed": "✅",
+		"failed":    "❌",
+		"invalid":   "⛔",
+		"skipped":   "⏭️ ",
+	}
+
+	fmt.Fprintln(msgOut, "\n📊 安装结果:")
+	fmt.Fprintln(msgOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Fprintf(msgOut, "   %-20s %-12s %-10s %s\n", "模块", "版本", "状态", "来源")
+	fmt.Fprintln(msgOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Status]++
+		id := result.ID
+		if id == "" {
+			id = "-"
+		}
+		fmt.Fprintf(msgOut, "%s %-20s %-12s %-10s %s\n", icons[result.Status], id, result.Version, result.Status, result.Source)
+		if result.Reason != "" {
+			// 校验错误可能有多行，缩进对齐
+			fmt.Fprintf(msgOut, "     %s\n", strings.ReplaceAll(result.Reason, "\n", "\n     "))
+		}
+	}
+
+	fmt.Fprintln(msgOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Fprintf(msgOut, "共 %d 个: 已安装 %d, 失败 %d, 校验失败 %d, 跳过 %d\n",
+		len(results), counts["installed"], counts["failed"], counts["invalid"], counts["skipped"])
+	if counts["installed"] > 0 {
+		fmt.Fprintln(msgOut, "🔄 需要重启设备后生效")
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 冲突处理策略
+const (
+	ConflictAsk      = "ask"      // 询问用户
+	ConflictAbort    = "abort"    // 中止安装
+	ConflictContinue = "continue" // 忽略冲突继续安装
+	ConflictDisable  = "disable"  // 禁用冲突的模块后继续安装
+)
+
+// overlayRoots 模块中会被挂载到系统分区的目录
+var overlayRoots = []string{"system", "vendor", "product"}
+
+// FileConflict 表示新模块与已安装模块提供了同一个文件
+type FileConflict struct {
+	Path    string   `json:"path"`    // 设备上的路径，如 /system/etc/hosts
+	Modules []string `json:"modules"` // 当前覆盖该路径的模块
+}
+
+// overlayTarget 将模块内的相对路径转换为设备上的路径
+// system/vendor 与 vendor、system/product 与 product 指向同一位置
+func overlayTarget(rel string) (string, bool) {
+	rel = strings.TrimPrefix(filepath.ToSlash(rel), "./")
+
+	for _, partition := range []string{"vendor", "product"} {
+		if strings.HasPrefix(rel, "system/"+partition+"/") {
+			return "/" + strings.TrimPrefix(rel, "system/"), true
+		}
+	}
+
+	for _, root := range overlayRoots {
+		if strings.HasPrefix(rel, root+"/") && len(rel) > len(root)+1 {
+			return "/" + rel, true
+		}
+	}
+	return "", false
+}
+
+// DetectConflicts 比较zip包与所有已启用模块覆盖的文件
+// 同ID的模块（即升级）、已禁用和待删除的模块不参与比较
+func (r *RMMD) DetectConflicts(zipInfo *ModuleZipInfo) ([]FileConflict, error) {
+	incoming := make(map[string]bool)
+	for _, name := range zipInfo.Files {
+		if strings.HasSuffix(name, "/") {
+			continue
+		}
+		if target, ok := overlayTarget(name); ok {
+			incoming[target] = true
+		}
+	}
+	if len(incoming) == 0 {
+		return nil, nil
+	}
+
+	modules, err := r.ListModules()
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make(map[string][]string)
+	for _, module := range modules {
+		if module.ID == zipInfo.ID || !module.Enabled || module.Remove {
+			continue
+		}
+
+		moduleDir := hostPath(filepath.Join(modulesDir, module.DirID))
+		for _, root := range overlayRoots {
+			rootDir := filepath.Join(moduleDir, root)
+			err := filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					if os.IsNotExist(err) {
+						return filepath.SkipDir
+					}
+					return err
+				}
+				if d.IsDir() {
+					return nil
+				}
+
+				rel, err := filepath.Rel(moduleDir, path)
+				if err != nil {
+					return err
+				}
+				if target, ok := overlayTarget(rel); ok && incoming[target] {
+					owners[target] = append(owners[target], module.ID)
+				}
+				return nil
+			})
+			if err != nil {
+				fmt.Fprintf(msgOut, "⚠️  扫描模块 %s 失败: %v\n", module.ID, err)
+			}
+		}
+	}
+
+	conflicts := make([]FileConflict, 0, len(owners))
+	for path, ids := range owners {
+		conflicts = append(conflicts, FileConflict{Path: path, Modules: ids})
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Path < conflicts[j].Path
+	})
+
+	return conflicts, nil
+}
+
+// conflictingModules 返回冲突涉及的所有模块ID
+func conflictingModules(conflicts []FileConflict) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, conflict := range conflicts {
+		for _, id := range conflict.Modules {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// printConflicts 打印文件冲突
+func printConflicts(moduleID string, conflicts []FileConflict) {
+	fmt.Fprintf(msgOut, "⚠️  模块 %s 与已安装的模块存在 %d 处文件冲突:\n", moduleID, len(conflicts))
+	for _, conflict := range conflicts {
+		fmt.Fprintf(msgOut, "   %s ← %s\n", conflict.Path, strings.Join(conflict.Modules, ", "))
+	}
+}
+
+// stdinIsTerminal 判断标准输入是否为终端，不是终端时无法询问用户
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// askConflictPolicy 询问用户如何处理冲突
+func askConflictPolicy() string {
+	fmt.Fprint(msgOut, "❓ 请选择: [a]中止安装 / [c]继续安装 / [d]禁用冲突模块后继续 [A/c/d]: ")
+
+	reader := bufio.NewReader(os.Stdin)
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		fmt.Fprintf(msgOut, "读取输入失败: %v\n", err)
+		return ConflictAbort
+	}
+
+	switch strings.TrimSpace(strings.ToLower(input)) {
+	case "c", "continue":
+		return ConflictContinue
+	case "d", "disable":
+		return ConflictDisable
+	default:
+		return ConflictAbort
+	}
+}
+
+// resolveConflicts 检测冲突并按策略处理，返回 nil 表示可以继续安装
+func (r *RMMD) resolveConflicts(zipInfo *ModuleZipInfo, policy string) error {
+	conflicts, err := r.DetectConflicts(zipInfo)
+	if err != nil {
+		return fmt.Errorf("冲突检测失败: %v", err)
+	}
+	if len(conflicts) == 0 {
+		fmt.Fprintln(msgOut, "✅ 未发现文件冲突")
+		return nil
+	}
+
+	printConflicts(zipInfo.ID, conflicts)
+
+	if policy == "" || policy == ConflictAsk {
+		policy = askConflictPolicy()
+	}
+
+	switch policy {
+	case ConflictContinue:
+		fmt.Fprintln(msgOut, "⏭️  忽略冲突，继续安装")
+		return nil
+	case ConflictDisable:
+		for _, id := range conflictingModules(conflicts) {
+			if _, err := r.DisableModule(id); err != nil {
+				return fmt.Errorf("禁用模块 %s 失败: %v", id, err)
+			}
+			fmt.Fprintf(msgOut, "🔴 已禁用冲突模块: %s\n", id)
+		}
+		return nil
+	case ConflictAbort:
+		return fmt.Errorf("存在文件冲突，已中止安装")
+	default:
+		return fmt.Errorf("未知的冲突处理策略: %s", policy)
+	}
+}
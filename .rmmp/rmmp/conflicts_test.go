@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOverlayTarget(t *testing.T) {
+	tests := []struct {
+		rel    string
+		want   string
+		wantOK bool
+	}{
+		{"system/bin/foo", "/system/bin/foo", true},
+		{"./system/etc/hosts", "/system/etc/hosts", true},
+		{"system/vendor/lib/libfoo.so", "/vendor/lib/libfoo.so", true},
+		{"system/product/app/Foo.apk", "/product/app/Foo.apk", true},
+		{"vendor/etc/foo.conf", "/vendor/etc/foo.conf", true},
+		{"system", "", false},
+		{"system/", "", false},
+		{"module.prop", "", false},
+		{"webroot/index.html", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := overlayTarget(tt.rel)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("overlayTarget(%q) = %q, %v, want %q, %v", tt.rel, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestDetectConflicts(t *testing.T) {
+	oldSysroot := sysroot
+	defer func() { sysroot = oldSysroot }()
+	sysroot = t.TempDir()
+
+	modules := []ModuleInfo{
+		{ID: "hosts", DirID: "hosts", Enabled: true},
+		{ID: "fonts", DirID: "fonts", Enabled: true},
+		{ID: "disabled", DirID: "disabled", Enabled: false},
+		{ID: "removed", DirID: "removed", Enabled: true, Remove: true},
+		{ID: "incoming", DirID: "incoming", Enabled: true},
+	}
+	files := map[string][]string{
+		"hosts":    {"system/etc/hosts", "system/vendor/etc/foo.conf"},
+		"fonts":    {"system/fonts/Roboto.ttf", "system/etc/hosts"},
+		"disabled": {"system/etc/hosts"},
+		"removed":  {"system/fonts/Roboto.ttf"},
+		"incoming": {"system/etc/hosts"},
+	}
+	for dir, names := range files {
+		for _, name := range names {
+			path := filepath.Join(sysroot, "modules", dir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name  string
+		files []string
+		want  []FileConflict
+	}{
+		{
+			name:  "no overlay files",
+			files: []string{"module.prop", "customize.sh", "system/"},
+		},
+		{
+			name:  "no conflicts",
+			files: []string{"system/bin/foo"},
+			want:  []FileConflict{},
+		},
+		{
+			name:  "shared file",
+			files: []string{"system/etc/hosts", "system/bin/foo"},
+			want: []FileConflict{
+				{Path: "/system/etc/hosts", Modules: []string{"fonts", "hosts"}},
+			},
+		},
+		{
+			name:  "vendor paths match across layouts",
+			files: []string{"vendor/etc/foo.conf", "system/fonts/Roboto.ttf"},
+			want: []FileConflict{
+				{Path: "/system/fonts/Roboto.ttf", Modules: []string{"fonts"}},
+				{Path: "/vendor/etc/foo.conf", Modules: []string{"hosts"}},
+			},
+		},
+	}
+
+	r := NewRMMDWithBackend(NewFakeBackend(modules...))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.DetectConflicts(&ModuleZipInfo{ID: "incoming", Files: tt.files})
+			if err != nil {
+				t.Fatalf("DetectConflicts: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
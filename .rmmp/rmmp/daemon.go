@@ -0,0 +1,508 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// 设置后CLI不使用守护进程，直接执行操作
+	noDaemonEnv = "RMMP_NO_DAEMON"
+	// 连接守护进程的超时时间，超时视为守护进程未运行
+	daemonDialTimeout = 500 * time.Millisecond
+	// 单条请求的最大长度
+	maxRPCMessageSize = 1 << 20
+	// 写入一条消息的超时时间，客户端不读取时断开连接，避免阻塞守护进程
+	daemonWriteTimeout = 5 * time.Second
+)
+
+// JSON-RPC 错误码
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcOperationError = -32000
+)
+
+// getSocketPath 返回守护进程的控制socket路径
+func getSocketPath() string {
+	return filepath.Join(getDataDir(), "rmmp.sock")
+}
+
+// rpcMessage JSON-RPC 2.0 消息，请求、响应和通知共用
+// 每条消息占一行，ID 为空的请求是通知，不需要响应
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError JSON-RPC 错误
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// DaemonInfo ping 的返回值
+type DaemonInfo struct {
+	Version string `json:"version"`
+	RootEnv string `json:"rootEnv"`
+	PID     int    `json:"pid"`
+}
+
+// ModuleEvent modules.changed 通知的内容
+type ModuleEvent struct {
+	Time time.Time `json:"time"`
+	// ID 模块目录名
+	ID string `json:"id"`
+	// File 模块目录中变化的文件（如 disable、remove），模块目录本身变化时为空
+	File string `json:"file,omitempty"`
+	Op   string `json:"op"` // created, removed, changed
+}
+
+// daemonInstallParams install 的参数
+type daemonInstallParams struct {
+	ZipPath        string `json:"zipPath"`
+	ConflictPolicy string `json:"conflictPolicy,omitempty"`
+	SkipDeps       bool   `json:"skipDeps,omitempty"`
+	// 以下字段由客户端解析安装来源时得到，写入安装记录和操作历史
+	Origin string `json:"origin,omitempty"`
+	Source string `json:"source,omitempty"`
+	ZipURL string `json:"zipUrl,omitempty"`
+	Proxy  string `json:"proxy,omitempty"`
+	// Dependencies update.json 中声明的依赖
+	Dependencies []string `json:"dependencies,omitempty"`
+}
+
+// daemonModuleParams enable/disable/uninstall/undo-uninstall 的参数
+type daemonModuleParams struct {
+	ID string `json:"id"`
+}
+
+// daemonUpgradeParams upgrade 的参数
+type daemonUpgradeParams struct {
+	// IDs 要升级的模块，为空时升级所有模块
+	IDs            []string `json:"ids,omitempty"`
+	ConflictPolicy string   `json:"conflictPolicy,omitempty"`
+	SkipDeps       bool     `json:"skipDeps,omitempty"`
+}
+
+// daemonUpdateParams checkUpdates 的参数
+type daemonUpdateParams struct {
+	Refresh bool `json:"refresh,omitempty"`
+}
+
+// Daemon 通过 Unix socket 提供模块管理操作
+// 所有操作共用一个 RMMD，并且串行执行，避免同时修改模块目录
+type Daemon struct {
+	rmmd *RMMD
+	mu   sync.Mutex
+
+	subMu       sync.Mutex
+	subscribers map[*daemonConn]bool
+}
+
+// daemonConn 客户端连接，写入需要加锁，因为模块变更通知可能与响应同时写入
+type daemonConn struct {
+	conn net.Conn
+	mu   sync.Mutex
+}
+
+// send 写入一条消息，超时未写完时返回错误
+func (c *daemonConn) send(msg *rpcMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(daemonWriteTimeout))
+	_, err = c.conn.Write(append(data, '\n'))
+	return err
+}
+
+// RunDaemon 启动守护进程，直到收到 SIGINT/SIGTERM
+func RunDaemon() error {
+	socketPath := getSocketPath()
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %v", err)
+	}
+
+	// 上次异常退出会留下socket文件，能连上说明已经有守护进程在运行
+	if _, err := os.Stat(socketPath); err == nil {
+		if conn, err := net.DialTimeout("unix", socketPath, daemonDialTimeout); err == nil {
+			conn.Close()
+			return fmt.Errorf("守护进程已在运行: %s", socketPath)
+		}
+		if err := os.Remove(socketPath); err != nil {
+			return fmt.Errorf("删除残留的socket失败: %v", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("监听socket失败: %v", err)
+	}
+	defer os.Remove(socketPath)
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("设置socket权限失败: %v", err)
+	}
+
+	d := &Daemon{
+		rmmd:        NewRMMD(),
+		subscribers: make(map[*daemonConn]bool),
+	}
+	fmt.Fprintf(msgOut, "🚀 rmmp 守护进程已启动 (PID %d): %s\n", os.Getpid(), socketPath)
+
+	// 没有Root管理器时由守护进程在开机后合并内置安装器暂存的模块
+	if _, native := d.rmmd.backend.(*NativeBackend); native && sysroot == "" {
+		if err := applyStagedModules(currentBootID()); err != nil {
+			fmt.Fprintf(msgOut, "⚠️  %v\n", err)
+		}
+	}
+
+	go func() {
+		watchDir := hostPath(modulesDir)
+		if err := watchModules(watchDir, d.broadcast); err != nil {
+			fmt.Fprintf(msgOut, "⚠️  无法监听模块目录，不会推送模块变更: %v\n", err)
+		}
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		fmt.Fprintf(msgOut, "🛑 收到 %v，正在退出...\n", sig)
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("接受连接失败: %v", err)
+		}
+		go d.serve(&daemonConn{conn: conn})
+	}
+}
+
+// serve 按顺序处理一个连接上的请求
+func (d *Daemon) serve(c *daemonConn) {
+	defer func() {
+		d.subMu.Lock()
+		delete(d.subscribers, c)
+		d.subMu.Unlock()
+		c.conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 64*1024), maxRPCMessageSize)
+	for scanner.Scan() {
+		var req rpcMessage
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			c.send(&rpcMessage{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: "无法解析请求: " + err.Error()}})
+			continue
+		}
+
+		result, rpcErr := d.handle(c, req.Method, req.Params)
+		if req.ID == nil {
+			continue
+		}
+		resp := &rpcMessage{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+		if rpcErr == nil {
+			data, err := json.Marshal(result)
+			if err != nil {
+				resp.Error = &rpcError{Code: rpcOperationError, Message: fmt.Sprintf("序列化结果失败: %v", err)}
+			} else {
+				resp.Result = data
+			}
+		}
+		if err := c.send(resp); err != nil {
+			return
+		}
+	}
+}
+
+// handle 执行一个方法
+func (d *Daemon) handle(c *daemonConn, method string, params json.RawMessage) (interface{}, *rpcError) {
+	decode := func(v interface{}) *rpcError {
+		if len(params) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(params, v); err != nil {
+			return &rpcError{Code: rpcInvalidParams, Message: "参数无效: " + err.Error()}
+		}
+		return nil
+	}
+	failed := func(err error) *rpcError {
+		return &rpcError{Code: rpcOperationError, Message: err.Error()}
+	}
+
+	switch method {
+	case "ping":
+		return DaemonInfo{Version: version, RootEnv: d.rmmd.getRootEnvName(), PID: os.Getpid()}, nil
+
+	case "subscribe":
+		d.subMu.Lock()
+		d.subscribers[c] = true
+		d.subMu.Unlock()
+		return true, nil
+
+	case "list":
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		modules, err := d.rmmd.ListModules()
+		if err != nil {
+			return nil, failed(err)
+		}
+		return modules, nil
+
+	case "checkUpdates":
+		var p daemonUpdateParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		d.mu.Lock()
+		modules, err := d.rmmd.ListModules()
+		d.mu.Unlock()
+		if err != nil {
+			return nil, failed(err)
+		}
+		// 检查更新只访问网络，不需要与其他操作串行
+		uc := NewUpdateChecker()
+		uc.refresh = p.Refresh
+		return uc.CheckModules(modules), nil
+
+	case "enable", "disable", "uninstall", "undo-uninstall":
+		var p daemonModuleParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		if p.ID == "" {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "缺少模块ID"}
+		}
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		fmt.Fprintf(msgOut, "🔧 %s %s\n", method, p.ID)
+		var result *ModuleActionResult
+		var err error
+		switch method {
+		case "enable":
+			result, err = d.rmmd.EnableModule(p.ID)
+		case "disable":
+			result, err = d.rmmd.DisableModule(p.ID)
+		case "uninstall":
+			result, err = d.rmmd.UninstallModule(p.ID)
+		case "undo-uninstall":
+			result, err = d.rmmd.UndoUninstallModule(p.ID)
+		}
+		if err != nil {
+			return nil, failed(err)
+		}
+		return result, nil
+
+	case "install":
+		var p daemonInstallParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		if !filepath.IsAbs(p.ZipPath) {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "zipPath 必须是绝对路径"}
+		}
+		// 守护进程无法询问用户，未指定策略时遇到冲突直接中止
+		if p.ConflictPolicy == "" || p.ConflictPolicy == ConflictAsk {
+			p.ConflictPolicy = ConflictAbort
+		}
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		fmt.Fprintf(msgOut, "📦 install %s\n", p.ZipPath)
+		opts := InstallOptions{
+			ConflictPolicy: p.ConflictPolicy,
+			SkipDeps:       p.SkipDeps,
+			Origin:         p.Origin,
+			Source:         p.Source,
+			ZipURL:         p.ZipURL,
+			Proxy:          p.Proxy,
+			Dependencies:   p.Dependencies,
+		}
+		if err := d.rmmd.InstallModuleWithDeps(p.ZipPath, opts); err != nil {
+			return nil, failed(err)
+		}
+		return true, nil
+
+	case "upgrade":
+		var p daemonUpgradeParams
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		if p.ConflictPolicy == "" || p.ConflictPolicy == ConflictAsk {
+			p.ConflictPolicy = ConflictAbort
+		}
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		fmt.Fprintf(msgOut, "⬆️  upgrade %v\n", p.IDs)
+		results, err := d.rmmd.UpgradeModules(p.IDs, InstallOptions{ConflictPolicy: p.ConflictPolicy, SkipDeps: p.SkipDeps})
+		if err != nil {
+			return nil, failed(err)
+		}
+		return results, nil
+	}
+
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: "未知的方法: " + method}
+}
+
+// broadcast 向订阅的客户端推送模块变更
+func (d *Daemon) broadcast(event ModuleEvent) {
+	params, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	msg := &rpcMessage{JSONRPC: "2.0", Method: "modules.changed", Params: params}
+
+	// 写入时不持有 subMu，一个不读取的客户端不会阻塞订阅和连接的清理
+	d.subMu.Lock()
+	subscribers := make([]*daemonConn, 0, len(d.subscribers))
+	for c := range d.subscribers {
+		subscribers = append(subscribers, c)
+	}
+	d.subMu.Unlock()
+
+	for _, c := range subscribers {
+		if err := c.send(msg); err != nil {
+			d.subMu.Lock()
+			delete(d.subscribers, c)
+			d.subMu.Unlock()
+			// 关闭连接后 serve 会退出并完成清理
+			c.conn.Close()
+		}
+	}
+}
+
+// DaemonClient 守护进程的客户端
+type DaemonClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	nextID int
+}
+
+// dialDaemon 连接正在运行的守护进程
+// 使用 --sysroot、--root-env 或设置了 RMMP_NO_DAEMON 时返回 nil，
+// 这些情况下守护进程的环境与当前命令不一致
+func dialDaemon() *DaemonClient {
+	if sysroot != "" || rootEnvOverride != "" || os.Getenv(noDaemonEnv) != "" {
+		return nil
+	}
+	conn, err := net.DialTimeout("unix", getSocketPath(), daemonDialTimeout)
+	if err != nil {
+		return nil
+	}
+	fmt.Fprintln(msgOut, "🔌 使用守护进程")
+	return &DaemonClient{conn: conn, reader: bufio.NewReaderSize(conn, 64*1024)}
+}
+
+// Close 断开连接
+func (c *DaemonClient) Close() error {
+	return c.conn.Close()
+}
+
+// Call 调用方法并将结果解码到 result，跳过等待期间收到的通知
+func (c *DaemonClient) Call(method string, params, result interface{}) error {
+	c.nextID++
+	id := json.RawMessage(fmt.Sprintf("%d", c.nextID))
+	req := &rpcMessage{JSONRPC: "2.0", ID: id, Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("序列化请求失败: %v", err)
+		}
+		req.Params = data
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %v", err)
+	}
+	if _, err := c.conn.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("发送请求失败: %v", err)
+	}
+
+	for {
+		line, err := c.reader.ReadBytes('\n')
+		if err != nil {
+			return fmt.Errorf("读取守护进程响应失败: %v", err)
+		}
+		var resp rpcMessage
+		if err := json.Unmarshal(line, &resp); err != nil {
+			return fmt.Errorf("解析守护进程响应失败: %v", err)
+		}
+		if resp.ID == nil || string(resp.ID) != string(id) {
+			continue
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("解析守护进程响应失败: %v", err)
+		}
+		return nil
+	}
+}
+
+// handleDaemonCommand 处理 daemon 命令
+func handleDaemonCommand(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "help", "-h", "--help":
+			showDaemonHelp()
+		default:
+			fmt.Fprintf(msgOut, "未知的参数: %s\n", args[0])
+			showDaemonHelp()
+		}
+		return
+	}
+
+	if err := RunDaemon(); err != nil {
+		fmt.Fprintf(msgOut, "❌ 守护进程退出: %v\n", err)
+	}
+}
+
+// showDaemonHelp 显示守护进程帮助信息
+func showDaemonHelp() {
+	fmt.Fprintln(msgOut, "用法: rmmp daemon")
+	fmt.Fprintln(msgOut, "")
+	fmt.Fprintf(msgOut, "在 %s 上以 JSON-RPC 2.0 (每行一条消息) 提供以下方法:\n", getSocketPath())
+	fmt.Fprintln(msgOut, "  ping                                          返回版本、Root环境和PID")
+	fmt.Fprintln(msgOut, "  list                                          列出已安装的模块")
+	fmt.Fprintln(msgOut, "  install {zipPath, conflictPolicy, skipDeps}   安装模块，冲突策略默认 abort")
+	fmt.Fprintln(msgOut, "  upgrade {ids, conflictPolicy, skipDeps}       升级模块，ids 为空时升级所有模块")
+	fmt.Fprintln(msgOut, "  enable {id} / disable {id}                    启用/禁用模块")
+	fmt.Fprintln(msgOut, "  uninstall {id} / undo-uninstall {id}          卸载模块/撤销卸载")
+	fmt.Fprintln(msgOut, "  checkUpdates {refresh}                        检查模块更新")
+	fmt.Fprintln(msgOut, "  subscribe                                     订阅 modules.changed 通知")
+	fmt.Fprintln(msgOut, "")
+	fmt.Fprintln(msgOut, "守护进程运行时，module list/outdated/enable/disable/uninstall/undo-uninstall 会自动通过它执行；")
+	fmt.Fprintln(msgOut, "install 和 upgrade 在不需要询问冲突处理方式时通过它执行（指定了 --on-conflict，或批量升级、无法交互时），")
+	fmt.Fprintln(msgOut, "需要询问时在本地执行。install --dry-run 和多个模块的 install 始终在本地执行。")
+	fmt.Fprintf(msgOut, "设置 %s=1 可以禁用\n", noDaemonEnv)
+}
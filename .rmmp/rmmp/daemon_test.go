@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDaemonModuleActions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	d := &Daemon{rmmd: NewRMMDWithBackend(NewFakeBackend(
+		ModuleInfo{ID: "demo", Name: "demo", Version: "v1", VersionCode: 1, Enabled: true},
+	))}
+
+	steps := []struct {
+		method      string
+		id          string
+		wantErr     bool
+		wantChanged bool
+	}{
+		{method: "disable", id: "demo", wantChanged: true},
+		{method: "enable", id: "demo", wantChanged: true},
+		{method: "uninstall", id: "demo", wantChanged: true},
+		{method: "uninstall", id: "demo"},
+		{method: "undo-uninstall", id: "demo", wantChanged: true},
+		{method: "uninstall", id: "missing", wantErr: true},
+		{method: "uninstall", wantErr: true},
+	}
+
+	for _, step := range steps {
+		params, _ := json.Marshal(daemonModuleParams{ID: step.id})
+		result, rpcErr := d.handle(nil, step.method, params)
+		if (rpcErr != nil) != step.wantErr {
+			t.Fatalf("%s %s: error = %v, wantErr %v", step.method, step.id, rpcErr, step.wantErr)
+		}
+		if rpcErr != nil {
+			continue
+		}
+		action := result.(*ModuleActionResult)
+		if action.Changed != step.wantChanged {
+			t.Errorf("%s %s: changed = %v, want %v", step.method, step.id, action.Changed, step.wantChanged)
+		}
+	}
+}
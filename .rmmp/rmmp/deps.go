@@ -0,0 +1,437 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// dependencyPattern 依赖声明: 模块ID [运算符 版本] [@ 来源]
+// 例如 "zygisk_lsposed>=6990"、"busybox @ user/repo"、"foo>=v1.2 @ https://example.com/update.json"
+var dependencyPattern = regexp.MustCompile(`^\s*([a-zA-Z][a-zA-Z0-9._-]+)\s*(?:(>=|<=|==|!=|>|<)\s*([^\s@]+))?\s*(?:@\s*(\S+))?\s*$`)
+
+// versionNumberPattern 版本号中的数字段
+var versionNumberPattern = regexp.MustCompile(`\d+`)
+
+// Dependency 表示模块声明的一个依赖
+type Dependency struct {
+	ID     string
+	Op     string // 为空表示任意版本
+	Value  string // 纯数字时与 versionCode 比较，否则与 version 比较
+	Source string // 仓库 (username/repo) 或 update.json 链接
+}
+
+// parseDependency 解析依赖声明
+func parseDependency(spec string) (Dependency, error) {
+	match := dependencyPattern.FindStringSubmatch(spec)
+	if match == nil {
+		return Dependency{}, fmt.Errorf("无效的依赖声明: %q", spec)
+	}
+	return Dependency{ID: match[1], Op: match[2], Value: match[3], Source: match[4]}, nil
+}
+
+// parseDependencies 解析一组依赖声明
+func parseDependencies(specs []string) ([]Dependency, error) {
+	deps := make([]Dependency, 0, len(specs))
+	for _, spec := range specs {
+		dep, err := parseDependency(spec)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// String 返回依赖的约束部分，如 foo>=120
+func (d Dependency) String() string {
+	return d.ID + d.Op + d.Value
+}
+
+// satisfiedBy 判断指定版本是否满足依赖的版本约束
+func (d Dependency) satisfiedBy(version string, versionCode int) bool {
+	if d.Op == "" {
+		return true
+	}
+
+	var cmp int
+	if code, err := strconv.Atoi(d.Value); err == nil {
+		cmp = versionCode - code
+	} else {
+		cmp = compareVersions(version, d.Value)
+	}
+
+	switch d.Op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	}
+	return false
+}
+
+// compareVersions 按数字段比较两个版本号，如 v1.10.0 > v1.9
+func compareVersions(a, b string) int {
+	as := versionNumberPattern.FindAllString(a, -1)
+	bs := versionNumberPattern.FindAllString(b, -1)
+
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// readZipDependencies 读取zip中 rmmproject.toml 的 [project] dependencies
+func readZipDependencies(file *zip.File) ([]string, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("无法读取 rmmproject.toml: %v", err)
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("无法读取 rmmproject.toml: %v", err)
+	}
+	return parseProjectDependencies(string(content))
+}
+
+// parseProjectDependencies 从 rmmproject.toml 中取出 [project] 表的 dependencies 字符串数组
+// 只解析这一个字段，数组可以跨多行并包含注释
+func parseProjectDependencies(content string) ([]string, error) {
+	inProject := false
+	var value strings.Builder
+	collecting := false
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !collecting {
+			if strings.HasPrefix(trimmed, "[") {
+				inProject = trimmed == "[project]"
+				continue
+			}
+			key, rest, ok := strings.Cut(trimmed, "=")
+			if !inProject || !ok || strings.TrimSpace(key) != "dependencies" {
+				continue
+			}
+			collecting = true
+			trimmed = rest
+		}
+
+		value.WriteString(trimmed)
+		value.WriteString("\n")
+		if done, err := tomlArrayClosed(value.String()); err != nil {
+			return nil, err
+		} else if done {
+			return parseTOMLStringArray(value.String())
+		}
+	}
+
+	if collecting {
+		return nil, fmt.Errorf("rmmproject.toml 的 dependencies 数组没有结束")
+	}
+	return nil, nil
+}
+
+// tomlArrayClosed 判断数组是否已经读到结尾的 ]
+func tomlArrayClosed(s string) (bool, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "[") {
+		return false, fmt.Errorf("rmmproject.toml 的 dependencies 必须是数组")
+	}
+
+	var quote rune
+	for i, c := range s {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			// 跳过注释到行尾
+			if j := strings.IndexByte(s[i:], '\n'); j >= 0 {
+				return tomlArrayClosed(s[:i] + s[i+j:])
+			}
+			return false, nil
+		case c == ']':
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// parseTOMLStringArray 解析只包含字符串的TOML数组
+func parseTOMLStringArray(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "[")
+
+	var items []string
+	for {
+		s = strings.TrimLeft(s, " \t\r\n,")
+		switch {
+		case s == "":
+			return nil, fmt.Errorf("rmmproject.toml 的 dependencies 数组没有结束")
+		case s[0] == ']':
+			return items, nil
+		case s[0] == '#':
+			end := strings.IndexByte(s, '\n')
+			if end < 0 {
+				return nil, fmt.Errorf("rmmproject.toml 的 dependencies 数组没有结束")
+			}
+			s = s[end:]
+		case s[0] == '"' || s[0] == '\'':
+			end := strings.IndexByte(s[1:], s[0])
+			if end < 0 {
+				return nil, fmt.Errorf("rmmproject.toml 的 dependencies 包含未结束的字符串")
+			}
+			items = append(items, s[1:end+1])
+			s = s[end+2:]
+		default:
+			return nil, fmt.Errorf("rmmproject.toml 的 dependencies 只能包含字符串")
+		}
+	}
+}
+
+// plannedModule 依赖解析中计划安装的模块
+type plannedModule struct {
+	ID          string
+	Version     string
+	VersionCode int
+	ZipPath     string
+	Source      string
+	ZipURL      string
+	Proxy       string
+	Deps        []Dependency
+}
+
+// dependencyResolver 解析依赖图并按拓扑顺序给出需要安装的模块
+type dependencyResolver struct {
+	r         *RMMD
+	md        *ModuleDownloader
+	installed map[string]ModuleInfo
+	records   map[string]InstallRecord
+	planned   map[string]*plannedModule
+	visiting  map[string]bool
+	order     []*plannedModule
+}
+
+// resolveDependencies 返回安装 root 之前需要先安装的模块，依赖在前
+// 版本约束无法满足时返回错误，循环依赖只给出警告（所有模块在重启后同时生效）
+func (r *RMMD) resolveDependencies(root *plannedModule) ([]*plannedModule, error) {
+	modules, err := r.ListModules()
+	if err != nil {
+		return nil, err
+	}
+	records, err := loadInstallRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &dependencyResolver{
+		r:         r,
+		md:        NewModuleDownloader(),
+		installed: make(map[string]ModuleInfo),
+		records:   records,
+		planned:   map[string]*plannedModule{root.ID: root},
+		visiting:  map[string]bool{root.ID: true},
+	}
+	for _, module := range modules {
+		res.installed[module.ID] = module
+	}
+
+	if err := res.visit(root); err != nil {
+		return nil, err
+	}
+	return res.order, nil
+}
+
+// visit 深度优先处理模块的依赖
+func (res *dependencyResolver) visit(node *plannedModule) error {
+	for _, dep := range node.Deps {
+		if planned, ok := res.planned[dep.ID]; ok {
+			if !dep.satisfiedBy(planned.Version, planned.VersionCode) {
+				return fmt.Errorf("%s 需要 %s，但将要安装的是 %s (%d)", node.ID, dep, planned.Version, planned.VersionCode)
+			}
+			if res.visiting[dep.ID] {
+				fmt.Fprintf(msgOut, "⚠️  检测到循环依赖: %s → %s\n", node.ID, dep.ID)
+			}
+			continue
+		}
+
+		installed, isInstalled := res.installed[dep.ID]
+		if isInstalled && !installed.Remove && dep.satisfiedBy(installed.Version, installed.VersionCode) {
+			if !installed.Enabled {
+				fmt.Fprintf(msgOut, "⚠️  依赖 %s 已安装但被禁用\n", dep.ID)
+			}
+			continue
+		}
+
+		planned, err := res.fetch(dep, installed)
+		if err != nil {
+			return fmt.Errorf("%s 的依赖 %s: %v", node.ID, dep, err)
+		}
+		if !dep.satisfiedBy(planned.Version, planned.VersionCode) {
+			return fmt.Errorf("%s 需要 %s，但可获取的最新版本是 %s (%d)", node.ID, dep, planned.Version, planned.VersionCode)
+		}
+
+		res.planned[dep.ID] = planned
+		res.visiting[dep.ID] = true
+		if err := res.visit(planned); err != nil {
+			return err
+		}
+		res.visiting[dep.ID] = false
+		res.order = append(res.order, planned)
+	}
+	return nil
+}
+
+// fetch 下载依赖的最新版本
+// 来源依次取依赖声明、已安装模块的 updateJson、之前的安装记录
+func (res *dependencyResolver) fetch(dep Dependency, installed ModuleInfo) (*plannedModule, error) {
+	source := dep.Source
+	if source == "" {
+		source = installed.UpdateJSON
+	}
+	if source == "" {
+		source = res.records[dep.ID].Source
+	}
+	if source == "" && installed.ID != "" {
+		return nil, fmt.Errorf("已安装的版本 %s (%d) 不满足要求且没有指定来源 (可写作 %s @ username/repo)", installed.Version, installed.VersionCode, dep)
+	}
+	if source == "" {
+		return nil, fmt.Errorf("未安装且没有指定来源 (可写作 %s @ username/repo)", dep)
+	}
+
+	planned := &plannedModule{ID: dep.ID}
+
+	var info *UpdateInfo
+	var err error
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		info, err = res.md.fetchUpdateJSON(source)
+	} else {
+		planned.Source = res.md.normalizeRepoName(source)
+		if planned.Source == "" {
+			return nil, fmt.Errorf("无效的仓库格式: %s", source)
+		}
+		info, err = res.md.downloadUpdateJSON(planned.Source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取更新信息失败: %v", err)
+	}
+
+	planned.Version = info.Version
+	planned.VersionCode = info.VersionCode
+	planned.ZipURL = info.ZipURL
+
+	// 版本不满足时不必下载
+	if !dep.satisfiedBy(planned.Version, planned.VersionCode) {
+		return planned, nil
+	}
+
+	planned.ZipPath, err = res.md.downloadModule(info)
+	if err != nil {
+		return nil, fmt.Errorf("下载模块失败: %v", err)
+	}
+	planned.Proxy = res.md.lastProxy
+
+	zipInfo, err := res.r.ValidateModuleZip(planned.ZipPath)
+	if err != nil {
+		return nil, err
+	}
+	if zipInfo.ID != dep.ID {
+		return nil, fmt.Errorf("下载的模块ID为 %s", zipInfo.ID)
+	}
+
+	planned.Deps, err = parseDependencies(append(info.Dependencies, zipInfo.Dependencies...))
+	if err != nil {
+		return nil, err
+	}
+	return planned, nil
+}
+
+// InstallModuleWithDeps 先按拓扑顺序安装缺失的依赖，再安装模块
+// opts.Dependencies 为 update.json 中声明的依赖，与zip中 rmmproject.toml 声明的合并
+func (r *RMMD) InstallModuleWithDeps(zipPath string, opts InstallOptions) error {
+	if opts.SkipDeps {
+		return r.InstallModule(zipPath, opts)
+	}
+
+	zipInfo, err := r.ValidateModuleZip(zipPath)
+	if err != nil {
+		return err
+	}
+
+	deps, err := parseDependencies(append(opts.Dependencies, zipInfo.Dependencies...))
+	if err != nil {
+		return err
+	}
+	if len(deps) == 0 {
+		return r.InstallModule(zipPath, opts)
+	}
+
+	root := &plannedModule{
+		ID:      zipInfo.ID,
+		Version: zipInfo.Props["version"],
+		ZipPath: zipPath,
+		Deps:    deps,
+	}
+	root.VersionCode, _ = parseIntString(zipInfo.Props["versionCode"])
+
+	fmt.Fprintf(msgOut, "🔗 正在解析 %d 个依赖...\n", len(deps))
+	order, err := r.resolveDependencies(root)
+	if err != nil {
+		return fmt.Errorf("依赖解析失败: %v (可使用 --skip-deps 跳过依赖检查)", err)
+	}
+
+	if len(order) == 0 {
+		fmt.Fprintln(msgOut, "✅ 依赖均已满足")
+	} else {
+		fmt.Fprintf(msgOut, "📦 需要先安装 %d 个依赖:\n", len(order))
+		for _, planned := range order {
+			fmt.Fprintf(msgOut, "   %s %s (%d)\n", planned.ID, planned.Version, planned.VersionCode)
+		}
+	}
+
+	for _, planned := range order {
+		fmt.Fprintf(msgOut, "\n🔗 正在安装依赖 %s...\n", planned.ID)
+		depOpts := InstallOptions{
+			ConflictPolicy: opts.ConflictPolicy,
+			Source:         planned.Source,
+			ZipURL:         planned.ZipURL,
+			Proxy:          planned.Proxy,
+		}
+		if err := r.InstallModule(planned.ZipPath, depOpts); err != nil {
+			return fmt.Errorf("安装依赖 %s 失败: %v", planned.ID, err)
+		}
+	}
+
+	if len(order) > 0 {
+		fmt.Fprintf(msgOut, "\n📦 正在安装 %s...\n", zipInfo.ID)
+	}
+	return r.InstallModule(zipPath, opts)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.0", "v1.0", 0},
+		{"v1.10.0", "v1.9", 1},
+		{"1.2", "1.2.1", -1},
+		{"1.2.0", "1.2", 0},
+		{"v2", "v10", -1},
+		{"26.1 (26100)", "26.1 (26000)", 1},
+	}
+
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseDependency(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Dependency
+		wantErr bool
+	}{
+		{spec: "busybox", want: Dependency{ID: "busybox"}},
+		{spec: "zygisk_lsposed>=6990", want: Dependency{ID: "zygisk_lsposed", Op: ">=", Value: "6990"}},
+		{spec: "foo < v1.2 @ user/repo", want: Dependency{ID: "foo", Op: "<", Value: "v1.2", Source: "user/repo"}},
+		{spec: "bar@https://example.com/update.json", want: Dependency{ID: "bar", Source: "https://example.com/update.json"}},
+		{spec: "1bad", wantErr: true},
+		{spec: "foo=>1", wantErr: true},
+		{spec: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseDependency(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDependency(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseDependency(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestDependencySatisfiedBy(t *testing.T) {
+	tests := []struct {
+		spec        string
+		version     string
+		versionCode int
+		want        bool
+	}{
+		{"foo", "v0.1", 1, true},
+		{"foo>=120", "v1.0", 120, true},
+		{"foo>=120", "v9.0", 119, false},
+		{"foo>120", "v1.0", 120, false},
+		{"foo<=120", "v1.0", 120, true},
+		{"foo<120", "v1.0", 119, true},
+		{"foo==120", "v1.0", 121, false},
+		{"foo!=120", "v1.0", 121, true},
+		// 非纯数字的约束与 version 比较
+		{"foo>=v1.9", "v1.10", 1, true},
+		{"foo<v1.9", "v1.10", 999, false},
+		{"foo==1.2", "v1.2.0", 0, true},
+	}
+
+	for _, tt := range tests {
+		dep, err := parseDependency(tt.spec)
+		if err != nil {
+			t.Fatalf("parseDependency(%q): %v", tt.spec, err)
+		}
+		if got := dep.satisfiedBy(tt.version, tt.versionCode); got != tt.want {
+			t.Errorf("%s satisfiedBy(%q, %d) = %v, want %v", tt.spec, tt.version, tt.versionCode, got, tt.want)
+		}
+	}
+}
+
+func TestParseProjectDependencies(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "single line",
+			content: "[project]\nid = \"foo\"\ndependencies = [\"bar>=10\", \"baz\"]\n",
+			want:    []string{"bar>=10", "baz"},
+		},
+		{
+			name: "multi line with comments",
+			content: "[project]\ndependencies = [\n" +
+				"    \"bar>=10\", # 需要新版\n" +
+				"    # \"old\",\n" +
+				"    'baz @ user/repo',\n" +
+				"]\n",
+			want: []string{"bar>=10", "baz @ user/repo"},
+		},
+		{
+			name:    "other table ignored",
+			content: "[build]\ndependencies = [\"ignored\"]\n[project]\nid = \"foo\"\n",
+		},
+		{
+			name:    "empty array",
+			content: "[project]\ndependencies = []\n",
+			want:    []string{},
+		},
+		{
+			name:    "unterminated",
+			content: "[project]\ndependencies = [\n  \"bar\",\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProjectDependencies(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
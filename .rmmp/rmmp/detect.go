@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 指定Root环境的环境变量
+const rootEnvEnv = "RMMP_ROOT_ENV"
+
+// rootEnvOverride 强制使用的Root环境名称，为空时自动检测
+// 可通过 --root-env 参数或 RMMP_ROOT_ENV 环境变量设置
+var rootEnvOverride = os.Getenv(rootEnvEnv)
+
+// RootVersion 执行管理器二进制得到的版本信息
+type RootVersion struct {
+	Variant     string `json:"variant"` // 分支名称，如 KernelSU Next、Magisk Kitsune
+	Version     string `json:"version"`
+	VersionCode int    `json:"versionCode"`
+	Active      bool   `json:"active"` // 守护进程或内核支持是否在工作
+}
+
+// RootProbe 单个Root环境的检测结果
+type RootProbe struct {
+	Backend     string `json:"backend"`
+	Marker      string `json:"marker"`
+	MarkerFound bool   `json:"markerFound"`
+	Binary      string `json:"binary,omitempty"`
+	Runnable    bool   `json:"runnable"` // 二进制可以执行并返回版本
+	RootVersion
+	MinVersionCode int    `json:"minVersionCode,omitempty"`
+	Problem        string `json:"problem,omitempty"`
+
+	factory RootBackendFactory
+}
+
+// DisplayName 返回带分支和版本的名称
+func (p RootProbe) DisplayName() string {
+	name := p.Backend
+	if p.Variant != "" {
+		name = p.Variant
+	}
+	if p.Version != "" {
+		name += " " + p.Version
+	}
+	if p.VersionCode > 0 {
+		name += fmt.Sprintf(" (%d)", p.VersionCode)
+	}
+	return name
+}
+
+// Supported 版本是否满足最低要求，版本号未知时视为满足
+func (p RootProbe) Supported() bool {
+	return p.MinVersionCode == 0 || p.VersionCode == 0 || p.VersionCode >= p.MinVersionCode
+}
+
+// conflicting 是否算作同时存在的另一个Root环境，内置安装器不算
+func (p RootProbe) conflicting() bool {
+	return p.MarkerFound && !p.factory.Fallback
+}
+
+// usable 是否可以作为自动检测的结果
+func (p RootProbe) usable() bool {
+	return p.MarkerFound && p.Runnable && p.Supported()
+}
+
+// probeRootBackends 检测所有已注册的Root环境
+// sysroot 模式下无法执行设备上的二进制，Active 表示二进制文件是否存在
+func (r *RMMD) probeRootBackends() []RootProbe {
+	probes := make([]RootProbe, 0, len(rootBackends))
+	for _, factory := range rootBackends {
+		probe := RootProbe{
+			Backend:        factory.Name,
+			Marker:         factory.Marker,
+			Binary:         factory.Binary,
+			MinVersionCode: factory.MinVersionCode,
+			factory:        factory,
+		}
+		probe.MarkerFound = factory.detect(r)
+
+		switch {
+		case !probe.MarkerFound:
+		case factory.Probe == nil:
+			probe.Runnable = true
+		case sysroot != "":
+			// 不执行二进制，二进制文件存在时优先选择
+			probe.Runnable = true
+			probe.Active = factory.Binary == "" || r.fileExists(factory.Binary)
+		default:
+			version, err := factory.Probe(factory.Binary)
+			if err != nil {
+				probe.Problem = err.Error()
+				break
+			}
+			probe.Runnable = true
+			probe.RootVersion = version
+			if !probe.Supported() {
+				probe.Problem = fmt.Sprintf("版本号 %d 低于支持的最低版本 %d", probe.VersionCode, probe.MinVersionCode)
+			}
+		}
+		probes = append(probes, probe)
+	}
+	return probes
+}
+
+// chooseRootBackend 从检测结果中选择Root环境，返回下标和选择原因
+// 指定了 rootEnvOverride 时直接使用；否则优先选择守护进程在工作的环境，
+// 其次是二进制可以执行的环境，再其次是只存在目录的环境，最后才使用内置安装器
+func chooseRootBackend(probes []RootProbe) (int, string, error) {
+	if rootEnvOverride != "" {
+		var names []string
+		for i, probe := range probes {
+			if strings.EqualFold(probe.Backend, rootEnvOverride) {
+				return i, "通过 --root-env/" + rootEnvEnv + " 指定", nil
+			}
+			names = append(names, probe.Backend)
+		}
+		return -1, "", fmt.Errorf("未知的Root环境 %s，可选: %s", rootEnvOverride, strings.Join(names, ", "))
+	}
+
+	for i, probe := range probes {
+		if probe.usable() && probe.Active && !probe.factory.Fallback {
+			if sysroot != "" {
+				return i, fmt.Sprintf("sysroot 中存在 %s 和 %s", probe.Marker, probe.Binary), nil
+			}
+			return i, "管理器可以执行且正在运行", nil
+		}
+	}
+	for i, probe := range probes {
+		if probe.usable() && !probe.factory.Fallback {
+			if sysroot != "" {
+				return i, "sysroot 中存在 " + probe.Marker, nil
+			}
+			return i, "管理器可以执行，但未检测到守护进程或内核支持", nil
+		}
+	}
+
+	var tooOld []string
+	for i, probe := range probes {
+		if !probe.MarkerFound || probe.factory.Fallback {
+			continue
+		}
+		if probe.Runnable && !probe.Supported() {
+			tooOld = append(tooOld, fmt.Sprintf("%s: %s", probe.DisplayName(), probe.Problem))
+			continue
+		}
+		return i, fmt.Sprintf("仅存在 %s，管理器无法执行", probe.Marker), nil
+	}
+
+	if len(tooOld) > 0 {
+		return -1, "", fmt.Errorf("Root管理器版本过低 (%s)，请升级后重试", strings.Join(tooOld, "; "))
+	}
+
+	for i, probe := range probes {
+		if probe.usable() && probe.factory.Fallback {
+			return i, "未检测到Root管理器，使用内置安装器", nil
+		}
+	}
+	return -1, "", fmt.Errorf("未检测到支持的Root环境")
+}
+
+// processRunning 检查是否有指定名称的进程在运行
+func processRunning(name string) bool {
+	comms, _ := filepath.Glob("/proc/[0-9]*/comm")
+	for _, comm := range comms {
+		data, err := os.ReadFile(comm)
+		if err == nil && strings.TrimSpace(string(data)) == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 诊断结果状态
+const (
+	DoctorOK   = "ok"
+	DoctorWarn = "warn"
+	DoctorFail = "fail"
+)
+
+// 网络可达性检测的超时时间
+const doctorNetworkTimeout = 5 * time.Second
+
+// DoctorCheck 单项诊断结果
+type DoctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Fix    string `json:"fix,omitempty"` // 建议的解决方法
+}
+
+// RunDoctor 执行全部环境诊断
+// 使用创建 RMMD 时的Root环境检测结果，不会再次执行管理器；诊断过程不创建任何文件或目录
+func (r *RMMD) RunDoctor() []DoctorCheck {
+	probes := r.probes
+	if probes == nil {
+		probes = r.probeRootBackends()
+	}
+	checks := []DoctorCheck{
+		checkRootEnvironment(probes),
+		checkManagerVersion(probes),
+		checkSELinux(),
+	}
+
+	dirs := []struct {
+		name string
+		dir  string
+	}{
+		{"数据目录", getDataDir()},
+		{"下载缓存目录", getDownloadCacheDir()},
+		{"代理缓存目录", filepath.Dir(getCacheFilePath())},
+	}
+	for _, d := range dirs {
+		checks = append(checks, checkWritable(d.name, d.dir))
+	}
+
+	checks = append(checks,
+		checkReachable("github.com", "https://github.com",
+			"无法直连GitHub时rmmp会自动尝试代理；可运行 rmmp proxy best 确认代理可用"),
+		checkReachable("代理API", githubProxyAPI,
+			"检查网络连接；代理API不可用时只能使用已缓存的代理列表"),
+		checkProxyCache(),
+	)
+	return checks
+}
+
+// checkRootEnvironment 说明选择了哪个Root环境以及原因
+func checkRootEnvironment(probes []RootProbe) DoctorCheck {
+	check := DoctorCheck{Name: "Root环境"}
+
+	index, reason, err := chooseRootBackend(probes)
+	if err != nil {
+		check.Status = DoctorFail
+		check.Detail = err.Error()
+		check.Fix = "确认设备已root，并在root shell中运行，例如 su -c rmmp doctor"
+		if sysroot != "" {
+			check.Fix = fmt.Sprintf("确认 sysroot %s 是从设备复制的 /data/adb 目录", sysroot)
+		} else if rootEnvOverride != "" {
+			check.Fix = "修改 --root-env 或 " + rootEnvEnv + " 的值"
+		} else if strings.Contains(check.Detail, "版本过低") {
+			check.Fix = "在管理器应用中升级Root方案"
+		}
+		return check
+	}
+
+	chosen := probes[index]
+	check.Status = DoctorOK
+	check.Detail = fmt.Sprintf("使用 %s，%s", chosen.DisplayName(), reason)
+	if !chosen.Runnable {
+		check.Status = DoctorWarn
+		check.Fix = fmt.Sprintf("%s 无法执行，可能是Root方案已卸载或安装不完整", chosen.Binary)
+	}
+
+	var others []string
+	for i, probe := range probes {
+		if i != index && probe.conflicting() {
+			others = append(others, probe.DisplayName())
+		}
+	}
+	if len(others) > 0 {
+		check.Status = DoctorWarn
+		check.Detail += "；同时存在: " + strings.Join(others, ", ")
+		check.Fix = "可能残留了其他Root方案的目录，确认不再使用后删除；也可用 --root-env 指定"
+	}
+	return check
+}
+
+// checkManagerVersion 检查Root管理器版本和运行状态
+func checkManagerVersion(probes []RootProbe) DoctorCheck {
+	check := DoctorCheck{Name: "管理器版本"}
+
+	index, _, err := chooseRootBackend(probes)
+	if err != nil {
+		check.Status = DoctorFail
+		check.Detail = "没有可用的Root管理器"
+		check.Fix = "先解决Root环境检测问题"
+		return check
+	}
+
+	chosen := probes[index]
+	switch {
+	case chosen.factory.Probe == nil:
+		check.Status = DoctorOK
+		check.Detail = chosen.Backend + "，不需要Root管理器"
+	case sysroot != "":
+		check.Status = DoctorWarn
+		check.Detail = "sysroot 模式下不执行 " + chosen.Binary
+		check.Fix = "sysroot 中的管理器无法在本机执行，可以忽略"
+	case !chosen.Runnable:
+		check.Status = DoctorFail
+		check.Detail = chosen.Problem
+		check.Fix = fmt.Sprintf("确认 %s 存在且可执行，或在管理器应用中重新安装", chosen.Binary)
+	case !chosen.Supported():
+		check.Status = DoctorFail
+		check.Detail = fmt.Sprintf("%s: %s", chosen.DisplayName(), chosen.Problem)
+		check.Fix = "在管理器应用中升级Root方案"
+	case !chosen.Active:
+		check.Status = DoctorWarn
+		check.Detail = chosen.DisplayName() + "，未检测到守护进程或内核支持"
+		check.Fix = "重启设备；仍然如此时在管理器应用中检查Root方案是否正常工作"
+	default:
+		check.Status = DoctorOK
+		check.Detail = chosen.DisplayName()
+	}
+	return check
+}
+
+// checkSELinux 读取SELinux模式
+func checkSELinux() DoctorCheck {
+	check := DoctorCheck{Name: "SELinux"}
+
+	mode := ""
+	if data, err := os.ReadFile("/sys/fs/selinux/enforce"); err == nil {
+		switch strings.TrimSpace(string(data)) {
+		case "1":
+			mode = "Enforcing"
+		case "0":
+			mode = "Permissive"
+		}
+	} else if output, err := exec.Command("getenforce").Output(); err == nil {
+		mode = strings.TrimSpace(string(output))
+	}
+
+	switch mode {
+	case "Enforcing":
+		check.Status = DoctorOK
+		check.Detail = mode
+	case "Permissive":
+		check.Status = DoctorWarn
+		check.Detail = mode
+		check.Fix = "Permissive 会掩盖模块的 sepolicy 问题；排查完成后执行 setenforce 1"
+	case "":
+		check.Status = DoctorWarn
+		check.Detail = "无法读取SELinux状态"
+		check.Fix = "在Android设备上以root运行；非Android环境可以忽略"
+	default:
+		check.Status = DoctorWarn
+		check.Detail = mode
+		check.Fix = "SELinux 未处于 Enforcing 模式，确认这是有意为之"
+	}
+	return check
+}
+
+// checkWritable 检查目录是否可写，目录不存在时检查能否在已存在的上级目录中创建
+func checkWritable(name, dir string) DoctorCheck {
+	check := DoctorCheck{Name: name}
+
+	existing, err := nearestExistingDir(dir)
+	if err == nil {
+		err = dirWritable(existing)
+	}
+
+	if err != nil {
+		check.Status = DoctorFail
+		check.Detail = fmt.Sprintf("%s 不可写: %v", dir, err)
+		check.Fix = "以root运行，并确认 /data 已挂载且存储空间充足"
+		return check
+	}
+
+	check.Status = DoctorOK
+	check.Detail = dir + " 可写"
+	if existing != filepath.Clean(dir) {
+		check.Detail = fmt.Sprintf("%s 尚未创建，%s 可写", dir, existing)
+	}
+	return check
+}
+
+// nearestExistingDir 返回 dir 本身或其最近的已存在的上级目录
+func nearestExistingDir(dir string) (string, error) {
+	path := filepath.Clean(dir)
+	for {
+		info, err := os.Stat(path)
+		if err == nil {
+			if !info.IsDir() {
+				return "", fmt.Errorf("%s 不是目录", path)
+			}
+			return path, nil
+		}
+		parent := filepath.Dir(path)
+		if !os.IsNotExist(err) || parent == path {
+			return "", err
+		}
+		path = parent
+	}
+}
+
+// checkReachable 检查网络地址是否可以访问
+func checkReachable(name, url, fix string) DoctorCheck {
+	check := DoctorCheck{Name: name}
+
+	ctx, cancel := context.WithTimeout(context.Background(), doctorNetworkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
+	if err == nil {
+		var resp *http.Response
+		resp, err = http.DefaultClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	if err != nil {
+		check.Status = DoctorFail
+		check.Detail = fmt.Sprintf("无法访问 %s: %v", url, err)
+		check.Fix = fix
+		return check
+	}
+
+	check.Status = DoctorOK
+	check.Detail = fmt.Sprintf("%s 可访问 (%dms)", url, time.Since(start).Milliseconds())
+	return check
+}
+
+// checkProxyCache 检查代理缓存的时间
+func checkProxyCache() DoctorCheck {
+	check := DoctorCheck{Name: "代理缓存"}
+
+	gpm := NewGitHubProxyManager()
+	cache, err := gpm.readCacheFile()
+	if err != nil {
+		check.Status = DoctorWarn
+		check.Detail = fmt.Sprintf("%s 不存在或无法读取", gpm.GetCacheFilePath())
+		check.Fix = "运行 rmmp proxy update 获取代理列表"
+		return check
+	}
+
+	age := time.Since(cache.CacheTime)
+	check.Detail = fmt.Sprintf("%d 个代理，%.1f 小时前更新", len(cache.Data), age.Hours())
+	switch {
+	case len(cache.Data) == 0:
+		check.Status = DoctorWarn
+		check.Fix = "代理列表为空，运行 rmmp proxy update 重新获取"
+	case age > cacheValidDuration:
+		check.Status = DoctorWarn
+		check.Fix = "缓存已过期，运行 rmmp proxy update 刷新"
+	default:
+		check.Status = DoctorOK
+	}
+	return check
+}
+
+// handleDoctorCommand 处理 doctor 命令
+func handleDoctorCommand() {
+	rmmd := NewRMMD()
+	checks := rmmd.RunDoctor()
+
+	if machineOutput() {
+		if err := printData(checks); err != nil {
+			fmt.Fprintf(msgOut, "❌ %v\n", err)
+		}
+		return
+	}
+
+	icons := map[string]string{
+		DoctorOK:   "✅",
+		DoctorWarn: "⚠️ ",
+		DoctorFail: "❌",
+	}
+
+	fmt.Fprintln(msgOut, "🩺 rmmp 环境诊断")
+	fmt.Fprintln(msgOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	problems := 0
+	for _, check := range checks {
+		fmt.Fprintf(msgOut, "%s %s: %s\n", icons[check.Status], check.Name, check.Detail)
+		if check.Fix != "" {
+			fmt.Fprintf(msgOut, "   💡 %s\n", check.Fix)
+		}
+		if check.Status != DoctorOK {
+			problems++
+		}
+	}
+	fmt.Fprintln(msgOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+
+	if problems == 0 {
+		fmt.Fprintln(msgOut, "✅ 未发现问题")
+	} else {
+		fmt.Fprintf(msgOut, "发现 %d 个问题\n", problems)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckWritable(t *testing.T) {
+	root := t.TempDir()
+	readOnly := filepath.Join(root, "ro")
+	file := filepath.Join(root, "file")
+	if err := os.Mkdir(readOnly, 0555); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		want string
+		// asUser root不受权限位限制，只在普通用户下检查
+		asUser bool
+	}{
+		{name: "existing", dir: root, want: DoctorOK},
+		{name: "missing", dir: filepath.Join(root, "a", "b"), want: DoctorOK},
+		{name: "under file", dir: filepath.Join(file, "a"), want: DoctorFail},
+		{name: "read-only parent", dir: filepath.Join(readOnly, "a"), want: DoctorFail, asUser: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.asUser && os.Geteuid() == 0 {
+				t.Skip("root 不受目录权限限制")
+			}
+			if check := checkWritable(tt.name, tt.dir); check.Status != tt.want {
+				t.Errorf("status = %s (%s), want %s", check.Status, check.Detail, tt.want)
+			}
+		})
+	}
+
+	// 诊断不能创建任何目录或文件
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("checkWritable created entries: %v", entries)
+	}
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,6 +14,7 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,6 +24,8 @@ type UpdateInfo struct {
 	Version     string `json:"version"`
 	VersionCode int    `json:"versionCode"`
 	ZipURL      string `json:"zipUrl"`
+	// Dependencies 依赖声明，格式同 rmmproject.toml 的 dependencies
+	Dependencies []string `json:"dependencies,omitempty"`
 }
 
 // ModuleDownloader 模块下载器
@@ -29,6 +34,13 @@ type ModuleDownloader struct {
 	cacheDir string
 	timeout  time.Duration
 	maxRetry int
+	quiet    bool
+
+	proxyMu sync.Mutex
+	proxies []GitHubProxyData
+
+	// lastProxy 最近一次下载成功时使用的代理，未使用代理时为空
+	lastProxy string
 }
 
 // NewModuleDownloader 创建新的模块下载器
@@ -54,6 +66,19 @@ func getDownloadCacheDir() string {
 	}
 }
 
+// getDataDir 获取rmmp的持久化数据目录（不随模块更新而被清除）
+func getDataDir() string {
+	if runtime.GOOS == "android" {
+		return "/data/adb/rmmp"
+	} else {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "./rmmp-data"
+		}
+		return filepath.Join(homeDir, "data", "adb", ".rmm", "rmmp")
+	}
+}
+
 // normalizeRepoName 规范化仓库名称 (支持 username/repo 和 username\repo)
 func (md *ModuleDownloader) normalizeRepoName(repo string) string {
 	// 将反斜杠替换为正斜杠
@@ -99,31 +124,45 @@ func (md *ModuleDownloader) downloadWithTimeout(url string, timeout time.Duratio
 
 // downloadUpdateJSON 下载update.json文件
 func (md *ModuleDownloader) downloadUpdateJSON(repo string) (*UpdateInfo, error) {
-	originalURL := md.buildUpdateURL(repo)
-	fmt.Printf("🔄 正在下载 %s 的更新信息...\n", repo)
+	fmt.Fprintf(msgOut, "🔄 正在下载 %s 的更新信息...\n", repo)
+	return md.fetchUpdateJSON(md.buildUpdateURL(repo))
+}
 
-	// 首先尝试原始链接
-	fmt.Printf("📡 尝试原始链接: %s\n", originalURL)
+// fetchUpdateJSON 下载并解析指定地址的update.json
+// 依次尝试原始链接、从代理链接中提取的GitHub链接以及GitHub代理
+func (md *ModuleDownloader) fetchUpdateJSON(originalURL string) (*UpdateInfo, error) {
+	md.logf("📡 尝试原始链接: %s\n", originalURL)
 	data, err := md.downloadWithTimeout(originalURL, md.timeout)
 	if err == nil {
-		fmt.Println("✅ 原始链接下载成功")
+		md.logf("✅ 原始链接下载成功\n")
 		return md.parseUpdateJSON(data)
 	}
 
-	fmt.Printf("⚠️  原始链接失败: %v\n", err)
-	fmt.Println("🔄 正在尝试代理链接...")
+	md.logf("⚠️  原始链接失败: %v\n", err)
 
-	// 获取代理列表并按速度排序
-	proxies, err := md.gpm.GetProxies()
+	githubURL := md.extractGitHubURL(originalURL)
+	if githubURL != originalURL {
+		md.logf("🔄 尝试提取的GitHub原始链接: %s\n", githubURL)
+		data, err = md.downloadWithTimeout(githubURL, md.timeout)
+		if err == nil {
+			md.logf("✅ GitHub原始链接下载成功\n")
+			return md.parseUpdateJSON(data)
+		}
+		md.logf("⚠️  GitHub原始链接失败: %v\n", err)
+	}
+
+	// GitHub代理只能加速GitHub的链接
+	if !isGitHubURL(githubURL) {
+		return nil, fmt.Errorf("下载update.json失败: %v", err)
+	}
+
+	md.logf("🔄 正在尝试代理链接...\n")
+
+	proxies, err := md.sortedProxies()
 	if err != nil {
 		return nil, fmt.Errorf("获取代理列表失败: %v", err)
 	}
 
-	// 按速度降序排序
-	sort.Slice(proxies, func(i, j int) bool {
-		return proxies[i].Speed > proxies[j].Speed
-	})
-
 	// 尝试每个代理
 	tried := 0
 	for _, proxy := range proxies {
@@ -131,23 +170,60 @@ func (md *ModuleDownloader) downloadUpdateJSON(repo string) (*UpdateInfo, error)
 			break
 		}
 
-		proxyURL := fmt.Sprintf("%s/%s", strings.TrimSuffix(proxy.URL, "/"), originalURL)
-		fmt.Printf("📡 尝试代理 [%d/%d]: %s (速度: %.2fMB/s, 延迟: %dms)\n",
+		proxyURL := fmt.Sprintf("%s/%s", strings.TrimSuffix(proxy.URL, "/"), githubURL)
+		md.logf("📡 尝试代理 [%d/%d]: %s (速度: %.2fMB/s, 延迟: %dms)\n",
 			tried+1, md.maxRetry, proxy.URL, proxy.Speed, proxy.Latency)
 
 		data, err := md.downloadWithTimeout(proxyURL, md.timeout)
 		if err == nil {
-			fmt.Printf("✅ 代理下载成功: %s\n", proxy.URL)
+			md.logf("✅ 代理下载成功: %s\n", proxy.URL)
 			return md.parseUpdateJSON(data)
 		}
 
-		fmt.Printf("❌ 代理失败: %v\n", err)
+		md.logf("❌ 代理失败: %v\n", err)
 		tried++
 	}
 
 	return nil, fmt.Errorf("所有下载尝试均失败")
 }
 
+// isGitHubURL 判断链接是否指向GitHub
+func isGitHubURL(url string) bool {
+	return strings.HasPrefix(url, "https://github.com/") ||
+		strings.HasPrefix(url, "https://raw.githubusercontent.com/")
+}
+
+// sortedProxies 获取按速度降序排列的代理列表，同一下载器内只获取一次
+func (md *ModuleDownloader) sortedProxies() ([]GitHubProxyData, error) {
+	md.proxyMu.Lock()
+	defer md.proxyMu.Unlock()
+
+	if md.proxies != nil {
+		return md.proxies, nil
+	}
+
+	proxies, err := md.gpm.GetProxies()
+	if err != nil {
+		return nil, err
+	}
+
+	// 按速度降序排序
+	sort.Slice(proxies, func(i, j int) bool {
+		return proxies[i].Speed > proxies[j].Speed
+	})
+
+	md.proxies = proxies
+	return proxies, nil
+}
+
+// logf 输出下载过程信息，静默模式下不输出
+func (md *ModuleDownloader) logf(format string, args ...interface{}) {
+	if md.quiet {
+		return
+	}
+	fmt.Fprintf(msgOut, format, args...)
+}
+
 // parseUpdateJSON 解析update.json内容
 func (md *ModuleDownloader) parseUpdateJSON(data []byte) (*UpdateInfo, error) {
 	var updateInfo UpdateInfo
@@ -169,41 +245,59 @@ func (md *ModuleDownloader) downloadModule(updateInfo *UpdateInfo) (string, erro
 		return "", fmt.Errorf("创建下载目录失败: %v", err)
 	}
 
-	// 生成本地文件名
-	fileName := fmt.Sprintf("module_%s_%d.zip",
+	// 生成本地文件名，不同模块的版本号可能相同，加上下载链接的哈希区分
+	fileName := fmt.Sprintf("module_%s_%d_%s.zip",
 		strings.ReplaceAll(updateInfo.Version, "/", "_"),
-		updateInfo.VersionCode)
+		updateInfo.VersionCode,
+		urlHash(updateInfo.ZipURL))
 	localPath := filepath.Join(md.cacheDir, fileName)
 
-	fmt.Printf("🔄 正在下载模块: %s\n", updateInfo.Version)
-	fmt.Printf("📁 保存位置: %s\n", localPath)
+	fmt.Fprintf(msgOut, "🔄 正在下载模块: %s\n", updateInfo.Version)
+	fmt.Fprintf(msgOut, "📁 保存位置: %s\n", localPath)
+
+	return md.downloadURL(updateInfo.ZipURL, localPath)
+}
+
+// urlHash 返回链接的短哈希，用于下载缓存的文件名
+func urlHash(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:8])
+}
+
+// downloadURL 下载文件，依次尝试原始链接、提取出的GitHub原始链接和代理
+func (md *ModuleDownloader) downloadURL(originalURL, localPath string) (string, error) {
+	md.lastProxy = ""
 
 	// 首先尝试原始链接
-	originalURL := updateInfo.ZipURL
-	fmt.Printf("📡 尝试原始链接下载...\n")
+	fmt.Fprintf(msgOut, "📡 尝试原始链接下载...\n")
 
 	err := md.downloadFile(originalURL, localPath, 30*time.Second) // 模块下载使用30秒超时
 	if err == nil {
-		fmt.Println("✅ 原始链接下载成功")
+		fmt.Fprintln(msgOut, "✅ 原始链接下载成功")
 		return localPath, nil
 	}
 
-	fmt.Printf("⚠️  原始链接下载失败: %v\n", err)
+	fmt.Fprintf(msgOut, "⚠️  原始链接下载失败: %v\n", err)
 
 	// 如果原始URL已经包含代理，尝试提取原始GitHub URL
 	githubURL := md.extractGitHubURL(originalURL)
 	if githubURL != originalURL {
-		fmt.Printf("🔄 尝试提取的GitHub原始链接: %s\n", githubURL)
+		fmt.Fprintf(msgOut, "🔄 尝试提取的GitHub原始链接: %s\n", githubURL)
 		err = md.downloadFile(githubURL, localPath, 30*time.Second)
 		if err == nil {
-			fmt.Println("✅ GitHub原始链接下载成功")
+			fmt.Fprintln(msgOut, "✅ GitHub原始链接下载成功")
 			return localPath, nil
 		}
-		fmt.Printf("⚠️  GitHub原始链接下载失败: %v\n", err)
+		fmt.Fprintf(msgOut, "⚠️  GitHub原始链接下载失败: %v\n", err)
+	}
+
+	// 代理只用于GitHub，其他链接（可能是内网地址）不能发送给第三方代理
+	if !isGitHubURL(githubURL) {
+		return "", fmt.Errorf("%v (非GitHub链接不使用代理)", err)
 	}
 
 	// 尝试代理下载
-	fmt.Println("🔄 正在尝试代理下载...")
+	fmt.Fprintln(msgOut, "🔄 正在尝试代理下载...")
 	return md.downloadWithProxies(githubURL, localPath)
 }
 
@@ -229,16 +323,11 @@ func (md *ModuleDownloader) extractGitHubURL(proxyURL string) string {
 
 // downloadWithProxies 使用代理下载文件
 func (md *ModuleDownloader) downloadWithProxies(originalURL, localPath string) (string, error) {
-	proxies, err := md.gpm.GetProxies()
+	proxies, err := md.sortedProxies()
 	if err != nil {
 		return "", fmt.Errorf("获取代理列表失败: %v", err)
 	}
 
-	// 按速度降序排序
-	sort.Slice(proxies, func(i, j int) bool {
-		return proxies[i].Speed > proxies[j].Speed
-	})
-
 	tried := 0
 	for _, proxy := range proxies {
 		if tried >= md.maxRetry {
@@ -246,15 +335,16 @@ func (md *ModuleDownloader) downloadWithProxies(originalURL, localPath string) (
 		}
 
 		proxyURL := fmt.Sprintf("%s/%s", strings.TrimSuffix(proxy.URL, "/"), originalURL)
-		fmt.Printf("📡 尝试代理 [%d/%d]: %s\n", tried+1, md.maxRetry, proxy.URL)
+		fmt.Fprintf(msgOut, "📡 尝试代理 [%d/%d]: %s\n", tried+1, md.maxRetry, proxy.URL)
 
 		err := md.downloadFile(proxyURL, localPath, 30*time.Second)
 		if err == nil {
-			fmt.Printf("✅ 代理下载成功: %s\n", proxy.URL)
+			fmt.Fprintf(msgOut, "✅ 代理下载成功: %s\n", proxy.URL)
+			md.lastProxy = proxy.URL
 			return localPath, nil
 		}
 
-		fmt.Printf("❌ 代理下载失败: %v\n", err)
+		fmt.Fprintf(msgOut, "❌ 代理下载失败: %v\n", err)
 		tried++
 	}
 
@@ -296,22 +386,22 @@ func (md *ModuleDownloader) downloadFile(url, localPath string, timeout time.Dur
 
 // confirmInstallation 确认是否安装模块
 func (md *ModuleDownloader) confirmInstallation(updateInfo *UpdateInfo, filePath string) bool {
-	fmt.Println("\n" + strings.Repeat("━", 60))
-	fmt.Println("📦 模块下载完成！")
-	fmt.Printf("📄 模块版本: %s\n", updateInfo.Version)
-	fmt.Printf("🔢 版本代码: %d\n", updateInfo.VersionCode)
-	fmt.Printf("📁 文件路径: %s\n", filePath)
+	fmt.Fprintln(msgOut, "\n"+strings.Repeat("━", 60))
+	fmt.Fprintln(msgOut, "📦 模块下载完成！")
+	fmt.Fprintf(msgOut, "📄 模块版本: %s\n", updateInfo.Version)
+	fmt.Fprintf(msgOut, "🔢 版本代码: %d\n", updateInfo.VersionCode)
+	fmt.Fprintf(msgOut, "📁 文件路径: %s\n", filePath)
 	if updateInfo.Changelog != "" {
-		fmt.Printf("📋 更新日志: %s\n", updateInfo.Changelog)
+		fmt.Fprintf(msgOut, "📋 更新日志: %s\n", updateInfo.Changelog)
 	}
-	fmt.Println(strings.Repeat("━", 60))
+	fmt.Fprintln(msgOut, strings.Repeat("━", 60))
 
-	fmt.Print("❓ 是否立即安装此模块？[Y/n]: ")
+	fmt.Fprint(msgOut, "❓ 是否立即安装此模块？[Y/n]: ")
 
 	reader := bufio.NewReader(os.Stdin)
 	input, err := reader.ReadString('\n')
 	if err != nil {
-		fmt.Printf("读取输入失败: %v\n", err)
+		fmt.Fprintf(msgOut, "读取输入失败: %v\n", err)
 		return false
 	}
 
@@ -320,43 +410,73 @@ func (md *ModuleDownloader) confirmInstallation(updateInfo *UpdateInfo, filePath
 }
 
 // handleGetCommand 处理get命令
-func handleGetCommand(repoArg string) {
+func handleGetCommand(repoArg string, opts InstallOptions) {
 	md := NewModuleDownloader()
 
 	// 规范化仓库名称
 	repo := md.normalizeRepoName(repoArg)
 	if repo == "" {
-		fmt.Printf("❌ 无效的仓库格式: %s\n", repoArg)
-		fmt.Println("正确格式: username/repo 或 username\\repo")
+		fmt.Fprintf(msgOut, "❌ 无效的仓库格式: %s\n", repoArg)
+		fmt.Fprintln(msgOut, "正确格式: username/repo 或 username\\repo")
 		return
 	}
 
-	fmt.Printf("🎯 目标仓库: %s\n", repo)
+	fmt.Fprintf(msgOut, "🎯 目标仓库: %s\n", repo)
 
 	// 下载update.json
 	updateInfo, err := md.downloadUpdateJSON(repo)
 	if err != nil {
-		fmt.Printf("❌ 下载更新信息失败: %v\n", err)
+		fmt.Fprintf(msgOut, "❌ 下载更新信息失败: %v\n", err)
 		return
 	}
 
-	fmt.Printf("✅ 获取到模块信息: %s (版本代码: %d)\n", updateInfo.Version, updateInfo.VersionCode)
+	fmt.Fprintf(msgOut, "✅ 获取到模块信息: %s (版本代码: %d)\n", updateInfo.Version, updateInfo.VersionCode)
+	if machineOutput() {
+		if err := printData(updateInfo); err != nil {
+			fmt.Fprintf(msgOut, "❌ %v\n", err)
+		}
+	}
 
 	// 下载模块文件
+	entry := HistoryEntry{
+		Action:        "get",
+		ToVersion:     updateInfo.Version,
+		ToVersionCode: updateInfo.VersionCode,
+		Source:        repo,
+	}
 	filePath, err := md.downloadModule(updateInfo)
+	entry.Proxy = md.lastProxy
 	if err != nil {
-		fmt.Printf("❌ 下载模块失败: %v\n", err)
+		entry.Outcome = OutcomeFailed
+		entry.Error = err.Error()
+		recordHistory(entry)
+		fmt.Fprintf(msgOut, "❌ 下载模块失败: %v\n", err)
 		return
 	}
+	entry.Sha256, _ = fileSHA256(filePath)
+	// 只读取模块ID，不需要检测Root环境
+	if zipInfo, err := (&RMMD{}).ValidateModuleZip(filePath); err == nil {
+		entry.ID = zipInfo.ID
+	}
 
 	// 确认安装
 	if md.confirmInstallation(updateInfo, filePath) {
-		fmt.Println("\n🚀 开始安装模块...")
-		installModule(filePath)
+		entry.Outcome = OutcomeSuccess
+		recordHistory(entry)
+
+		fmt.Fprintln(msgOut, "\n🚀 开始安装模块...")
+		opts.Source = repo
+		opts.ZipURL = updateInfo.ZipURL
+		opts.Proxy = md.lastProxy
+		opts.Dependencies = updateInfo.Dependencies
+		installModule(filePath, opts)
 	} else {
-		fmt.Println("⏸️  已取消安装，模块文件已保存")
-		fmt.Printf("📁 文件位置: %s\n", filePath)
-		fmt.Println("💡 您可以稍后使用以下命令手动安装:")
-		fmt.Printf("   rmmp module install \"%s\"\n", filePath)
+		entry.Outcome = OutcomeCancelled
+		recordHistory(entry)
+
+		fmt.Fprintln(msgOut, "⏸️  已取消安装，模块文件已保存")
+		fmt.Fprintf(msgOut, "📁 文件位置: %s\n", filePath)
+		fmt.Fprintln(msgOut, "💡 您可以稍后使用以下命令手动安装:")
+		fmt.Fprintf(msgOut, "   rmmp module install \"%s\"\n", filePath)
 	}
 }
@@ -0,0 +1,506 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 试运行时拦截的命令
+// 只读命令直接执行；写入命令只有参数规范化后都在沙盒内时才执行；其余命令只记录不执行。
+// 拦截只用于生成报告，无法拦截重定向和以绝对路径调用的命令，真正的隔离由 dryRunIsolation 提供
+var (
+	dryRunReadCommands = []string{
+		"basename", "cat", "cut", "date", "dirname", "expr", "getprop", "grep", "head",
+		"id", "ls", "md5sum", "readlink", "realpath", "sha1sum", "sha256sum",
+		"sleep", "sort", "stat", "tail", "tr", "uname", "uniq", "wc", "which",
+	}
+	dryRunWriteCommands = []string{
+		"awk", "busybox", "chmod", "cp", "find", "gzip", "ln", "mkdir", "mv", "rm",
+		"rmdir", "sed", "tar", "tee", "touch", "unzip", "xargs",
+	}
+	dryRunBlockedCommands = []string{
+		"am", "apd", "chattr", "chcon", "chown", "cmd", "curl", "dd", "insmod", "kill",
+		"killall", "ksud", "magisk", "magiskpolicy", "mount", "pkill", "pm", "reboot",
+		"resetprop", "rmmod", "service", "setenforce", "setprop", "settings", "start",
+		"stop", "su", "supolicy", "svc", "umount", "wget",
+	}
+)
+
+// DryRunFile 安装后模块目录中的文件
+type DryRunFile struct {
+	Path string `json:"path"`
+	Mode string `json:"mode"`
+	Size int64  `json:"size"`
+	Link string `json:"link,omitempty"` // 符号链接目标
+}
+
+// DryRunPermission 安装脚本通过 set_perm/set_perm_recursive 设置的权限
+type DryRunPermission struct {
+	Path      string `json:"path"`
+	Owner     string `json:"owner"` // uid:gid
+	Mode      string `json:"mode"`  // 递归时为目录权限
+	FileMode  string `json:"fileMode,omitempty"`
+	Context   string `json:"context,omitempty"`
+	Recursive bool   `json:"recursive"`
+}
+
+// DryRunCommand 安装脚本执行的外部命令
+type DryRunCommand struct {
+	Command string `json:"command"`
+	Blocked bool   `json:"blocked"` // 可能修改沙盒外的内容，未执行
+}
+
+// DryRunReport 试运行安装的结果
+type DryRunReport struct {
+	Source      string             `json:"source"`
+	ID          string             `json:"id,omitempty"`
+	Version     string             `json:"version,omitempty"`
+	Result      string             `json:"result"` // ok, aborted, failed
+	Error       string             `json:"error,omitempty"`
+	Output      []string           `json:"output"` // ui_print 输出
+	Files       []DryRunFile       `json:"files"`
+	Permissions []DryRunPermission `json:"permissions"`
+	Props       []PropEntry        `json:"props"`    // system.prop
+	Sepolicy    []string           `json:"sepolicy"` // sepolicy.rule
+	Commands    []DryRunCommand    `json:"commands"`
+}
+
+// DryRunInstall 在临时目录中模拟安装模块
+// customize.sh 在独立的挂载、网络和进程命名空间中执行，除沙盒外的文件系统都是只读的；
+// 同时使用记录参数的 ui_print/abort/set_perm 函数，PATH 前面加入拦截命令的脚本
+func DryRunInstall(zipPath string) (*DryRunReport, error) {
+	wrapper, err := dryRunWrapper()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DryRunReport{
+		Result:      "ok",
+		Output:      []string{},
+		Files:       []DryRunFile{},
+		Permissions: []DryRunPermission{},
+		Props:       []PropEntry{},
+		Sepolicy:    []string{},
+		Commands:    []DryRunCommand{},
+	}
+
+	root, err := os.MkdirTemp("", "rmmp-dryrun-")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(root)
+	// 拦截脚本比较的是规范化后的路径
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return nil, fmt.Errorf("解析临时目录失败: %v", err)
+	}
+	if zipPath, err = filepath.EvalSymlinks(zipPath); err != nil {
+		return nil, fmt.Errorf("解析模块路径失败: %v", err)
+	}
+
+	modPath := filepath.Join(root, "modules_update", "module")
+	tmpDir := filepath.Join(root, "tmp")
+	binDir := filepath.Join(root, "bin")
+	logDir := filepath.Join(root, "log")
+	for _, dir := range []string{modPath, tmpDir, binDir, logDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("创建临时目录失败: %v", err)
+		}
+	}
+	if err := writeDryRunShims(binDir); err != nil {
+		return nil, err
+	}
+
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("打开模块文件失败: %v", err)
+	}
+	defer reader.Close()
+
+	script := &moduleInstallScript{
+		zipPath: zipPath,
+		modPath: modPath,
+		helpers: dryRunIsolation + dryRunHelpers,
+		tmpDir:  tmpDir,
+		env: []string{
+			"PATH=" + binDir + string(os.PathListSeparator) + os.Getenv("PATH"),
+			"RMMP_HOST_PATH=" + os.Getenv("PATH"),
+			"RMMP_DRYRUN_ROOT=" + root,
+			"RMMP_DRYRUN_LOG=" + logDir,
+		},
+		wrapper: wrapper,
+	}
+	if err := script.extract(&reader.Reader); err != nil {
+		return nil, err
+	}
+
+	if err := script.run(); err != nil {
+		report.Result = "failed"
+		report.Error = err.Error()
+	}
+	// 隔离成功时 isolation 为空文件，不存在说明 unshare 没有执行脚本
+	if message, err := os.ReadFile(filepath.Join(logDir, "isolation")); err != nil {
+		return nil, fmt.Errorf("无法隔离安装脚本，未执行: %s", report.Error)
+	} else if len(message) > 0 {
+		return nil, fmt.Errorf("无法隔离安装脚本，未执行: %s", strings.TrimSpace(string(message)))
+	}
+	if message, err := os.ReadFile(filepath.Join(logDir, "abort")); err == nil {
+		report.Result = "aborted"
+		report.Error = strings.TrimSpace(string(message))
+	}
+	removeInstallOnlyFiles(modPath)
+
+	report.Output = readLogLines(filepath.Join(logDir, "messages"))
+	for _, line := range readLogLines(filepath.Join(logDir, "commands")) {
+		status, command, _ := strings.Cut(line, "\t")
+		// 沙盒路径对用户没有意义，换成脚本中的变量名
+		command = strings.ReplaceAll(command, modPath, "$MODPATH")
+		command = strings.ReplaceAll(command, tmpDir, "$TMPDIR")
+		report.Commands = append(report.Commands, DryRunCommand{Command: command, Blocked: status == "blocked"})
+	}
+	for _, line := range readLogLines(filepath.Join(logDir, "perms")) {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 7 {
+			continue
+		}
+		report.Permissions = append(report.Permissions, DryRunPermission{
+			Path:      sandboxRelPath(modPath, fields[1]),
+			Owner:     fields[2] + ":" + fields[3],
+			Mode:      fields[4],
+			FileMode:  fields[5],
+			Context:   fields[6],
+			Recursive: fields[0] == "recursive",
+		})
+	}
+
+	report.Files, err = listDryRunFiles(modPath)
+	if err != nil {
+		return nil, err
+	}
+	if content, err := os.ReadFile(filepath.Join(modPath, "system.prop")); err == nil {
+		report.Props = append(report.Props, ParseModuleProp(string(content)).Entries...)
+	}
+	if content, err := os.ReadFile(filepath.Join(modPath, "sepolicy.rule")); err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				report.Sepolicy = append(report.Sepolicy, line)
+			}
+		}
+	}
+	return report, nil
+}
+
+// dryRunWrapper 返回在新的挂载、网络和进程命名空间中执行安装脚本的 unshare 命令
+// 非root时同时创建用户命名空间
+func dryRunWrapper() ([]string, error) {
+	unshare, err := exec.LookPath("unshare")
+	if err != nil {
+		return nil, fmt.Errorf("试运行需要 unshare 命令来隔离安装脚本")
+	}
+	wrapper := []string{unshare, "-m", "-n", "-p", "-f"}
+	if os.Geteuid() != 0 {
+		wrapper = append(wrapper, "-r")
+	}
+	return wrapper, nil
+}
+
+// dryRunIsolation 在执行 customize.sh 之前隔离文件系统，失败时写入 isolation 并退出
+// 挂载传播设为私有后，用只包含 null/zero/random 等节点的 tmpfs 覆盖 /dev
+// （binder、init 的 socket 和块设备都不可见，setprop、pm 等无法修改系统），
+// 再将除沙盒外的所有挂载点重新挂载为只读，并确认能访问到的挂载点没有遗漏
+const dryRunIsolation = `rmmp_isolate() {
+  root=$RMMP_DRYRUN_ROOT
+  mount --make-rprivate / 2>/dev/null || mount -o rprivate / 2>/dev/null
+  if grep -q ' shared:' /proc/self/mountinfo; then
+    echo "无法将挂载传播设为私有"; return 1
+  fi
+  mount --bind "$root" "$root" || { echo "无法绑定沙盒目录"; return 1; }
+
+  mkdir "$root/.dev" && mount --rbind /dev "$root/.dev" || { echo "无法绑定 /dev"; return 1; }
+  # 用户命名空间中继承的挂载不能卸载，只能被新的 tmpfs 覆盖
+  umount -l /dev 2>/dev/null
+  mount -t tmpfs -o mode=755 tmpfs /dev || { echo "无法替换 /dev"; return 1; }
+  for node in null zero full random urandom tty; do
+    [ -e "$root/.dev/$node" ] || continue
+    : > "/dev/$node" && mount --bind "$root/.dev/$node" "/dev/$node" || { echo "无法绑定 /dev/$node"; return 1; }
+  done
+  if [ -d "$root/.dev/__properties__" ]; then
+    mkdir /dev/__properties__ && mount --bind "$root/.dev/__properties__" /dev/__properties__ ||
+      { echo "无法绑定 /dev/__properties__"; return 1; }
+  fi
+  umount -l "$root/.dev" && rmdir "$root/.dev" || { echo "无法卸载 $root/.dev"; return 1; }
+
+  while read -r _ mnt _; do
+    mnt=$(printf '%b' "$mnt")
+    [ "$mnt" = "$root" ] || mount -o remount,bind,ro "$mnt" 2>/dev/null
+  done < /proc/self/mounts
+  # 被后来的挂载覆盖的挂载点无法访问，其余都必须是只读的
+  awk -v root="$root" '
+    { mnt[NR] = $2; opts[NR] = "," $4 "," }
+    END {
+      for (i = 1; i <= NR; i++) {
+        if (mnt[i] == root || opts[i] ~ /,ro,/) continue
+        covered = 0
+        for (j = i + 1; j <= NR && !covered; j++)
+          covered = mnt[j] == mnt[i] || mnt[j] == "/" || index(mnt[i], mnt[j] "/") == 1
+        if (!covered) { print "无法将 " mnt[i] " 设为只读"; exit 1 }
+      }
+    }' /proc/self/mounts
+}
+# 输出不能重定向到文件，打开的文件会导致只读重挂载失败
+if ! message=$(PATH=$RMMP_HOST_PATH; rmmp_isolate 2>&1); then
+  echo "${message:-隔离失败}" > "$RMMP_DRYRUN_LOG/isolation"
+  exit 97
+fi
+: > "$RMMP_DRYRUN_LOG/isolation"
+`
+
+// dryRunHelpers 记录参数而不修改系统的安装函数
+const dryRunHelpers = `ui_print() { echo "$1"; printf '%s\n' "$1" >> "$RMMP_DRYRUN_LOG/messages"; }
+abort() { ui_print "$1"; printf '%s\n' "$1" > "$RMMP_DRYRUN_LOG/abort"; exit 1; }
+set_perm() {
+  printf 'single\t%s\t%s\t%s\t%s\t\t%s\n' "$1" "$2" "$3" "$4" "$5" >> "$RMMP_DRYRUN_LOG/perms"
+}
+set_perm_recursive() {
+  [ -e "$1" ] || return 0
+  printf 'recursive\t%s\t%s\t%s\t%s\t%s\t%s\n' "$1" "$2" "$3" "$4" "$5" "$6" >> "$RMMP_DRYRUN_LOG/perms"
+}
+mkwhiteout() { printf 'run\tmknod %s c 0 0\n' "$1" >> "$RMMP_DRYRUN_LOG/commands"; : > "$1"; }
+`
+
+// dryRunShim 拦截命令的脚本，MODE 为 read、write 或 block
+// 写入命令的每个参数（相对路径按当前目录）都用 realpath -m 规范化后再判断是否在沙盒内，
+// 不是路径的参数在当前目录位于沙盒内时也会落在沙盒内，因此不会误判
+const dryRunShim = `#!/bin/sh
+status=run
+[ "$MODE" = block ] || [ -z "$REAL" ] && status=blocked
+check_path() {
+  [ -n "$1" ] || return 0
+  real=$("$REALPATH" -m "$1" 2>/dev/null) || { status=blocked; return 0; }
+  case "$real" in
+    "$MODPATH"|"$MODPATH"/*|"$TMPDIR"|"$TMPDIR"/*|"$ZIPFILE"|/dev/null) ;;
+    *) status=blocked ;;
+  esac
+}
+if [ "$MODE" = write ]; then
+  for arg in "$@"; do
+    case "$arg" in
+      -*=*) check_path "${arg#*=}" ;;
+      -*) ;;
+      *=*) check_path "$arg"; check_path "${arg#*=}" ;;
+      *) check_path "$arg" ;;
+    esac
+  done
+fi
+printf '%s\t%s\n' "$status" "$NAME $*" >> "$RMMP_DRYRUN_LOG/commands"
+[ "$status" = run ] && exec "$REAL" "$@"
+exit 0
+`
+
+// writeDryRunShims 在 binDir 中为每个拦截的命令生成脚本
+func writeDryRunShims(binDir string) error {
+	groups := map[string][]string{
+		"read":  dryRunReadCommands,
+		"write": dryRunWriteCommands,
+		"block": dryRunBlockedCommands,
+	}
+	realpath, _ := exec.LookPath("realpath")
+	for mode, names := range groups {
+		for _, name := range names {
+			real := ""
+			if mode != "block" {
+				real, _ = exec.LookPath(name)
+			}
+			header := fmt.Sprintf("NAME=%s\nMODE=%s\nREAL='%s'\nREALPATH='%s'\n", name, mode, real, realpath)
+			content := strings.Replace(dryRunShim, "#!/bin/sh\n", "#!/bin/sh\n"+header, 1)
+			if err := os.WriteFile(filepath.Join(binDir, name), []byte(content), 0755); err != nil {
+				return fmt.Errorf("创建命令拦截脚本失败: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// listDryRunFiles 列出模块目录中的文件
+func listDryRunFiles(modPath string) ([]DryRunFile, error) {
+	files := []DryRunFile{}
+	err := filepath.WalkDir(modPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == modPath || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		file := DryRunFile{
+			Path: sandboxRelPath(modPath, path),
+			Mode: fmt.Sprintf("%04o", info.Mode().Perm()),
+			Size: info.Size(),
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			file.Link, _ = os.Readlink(path)
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取模块目录失败: %v", err)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// sandboxRelPath 将沙盒中的路径转换为相对于模块目录的路径
+func sandboxRelPath(modPath, path string) string {
+	if path == modPath {
+		return "."
+	}
+	if rel, err := filepath.Rel(modPath, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// readLogLines 读取日志文件的所有行，文件不存在时返回空
+func readLogLines(path string) []string {
+	lines := []string{}
+	file, err := os.Open(path)
+	if err != nil {
+		return lines
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// printDryRunReport 打印试运行结果
+func printDryRunReport(report *DryRunReport) {
+	fmt.Fprintf(msgOut, "\n🧪 试运行结果: %s (%s)\n", report.ID, report.Version)
+	fmt.Fprintln(msgOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+
+	fmt.Fprintf(msgOut, "📁 将放置 %d 个文件:\n", len(report.Files))
+	for _, file := range report.Files {
+		line := fmt.Sprintf("   %s %8d  %s", file.Mode, file.Size, file.Path)
+		if file.Link != "" {
+			line += " -> " + file.Link
+		}
+		fmt.Fprintln(msgOut, line)
+	}
+
+	if len(report.Permissions) > 0 {
+		fmt.Fprintf(msgOut, "🔐 设置 %d 项权限:\n", len(report.Permissions))
+		for _, perm := range report.Permissions {
+			mode := perm.Mode
+			if perm.Recursive {
+				mode = fmt.Sprintf("目录 %s 文件 %s", perm.Mode, perm.FileMode)
+			}
+			line := fmt.Sprintf("   %s  %s  %s", perm.Path, perm.Owner, mode)
+			if perm.Context != "" {
+				line += "  " + perm.Context
+			}
+			fmt.Fprintln(msgOut, line)
+		}
+	}
+
+	if len(report.Props) > 0 {
+		fmt.Fprintf(msgOut, "⚙️  添加 %d 个系统属性 (system.prop):\n", len(report.Props))
+		for _, prop := range report.Props {
+			fmt.Fprintf(msgOut, "   %s=%s\n", prop.Key, prop.Value)
+		}
+	}
+
+	if len(report.Sepolicy) > 0 {
+		fmt.Fprintf(msgOut, "🛡️  添加 %d 条 sepolicy 规则:\n", len(report.Sepolicy))
+		for _, rule := range report.Sepolicy {
+			fmt.Fprintf(msgOut, "   %s\n", rule)
+		}
+	}
+
+	if len(report.Commands) > 0 {
+		blocked := 0
+		for _, command := range report.Commands {
+			if command.Blocked {
+				blocked++
+			}
+		}
+		fmt.Fprintf(msgOut, "💻 执行了 %d 个命令 (%d 个被拦截):\n", len(report.Commands), blocked)
+		for _, command := range report.Commands {
+			icon := "  "
+			if command.Blocked {
+				icon = "⛔"
+			}
+			fmt.Fprintf(msgOut, "   %s %s\n", icon, command.Command)
+		}
+	}
+
+	fmt.Fprintln(msgOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	switch report.Result {
+	case "ok":
+		fmt.Fprintln(msgOut, "✅ 安装脚本执行成功 (在隔离环境中运行，沙盒之外的文件系统为只读)")
+	case "aborted":
+		fmt.Fprintf(msgOut, "⛔ 安装脚本中止: %s\n", report.Error)
+	default:
+		fmt.Fprintf(msgOut, "❌ %s\n", report.Error)
+	}
+}
+
+// handleDryRunInstall 试运行安装多个来源的模块
+func handleDryRunInstall(sources []string, opts InstallOptions) {
+	reports := []*DryRunReport{}
+	for _, source := range sources {
+		report, err := dryRunSource(source, opts)
+		if err != nil {
+			fmt.Fprintf(msgOut, "❌ %s: %v\n", source, err)
+			report = &DryRunReport{Source: source, Result: "failed", Error: err.Error()}
+		}
+		reports = append(reports, report)
+		if err == nil && !machineOutput() {
+			printDryRunReport(report)
+		}
+	}
+
+	if machineOutput() {
+		if err := printData(reports); err != nil {
+			fmt.Fprintf(msgOut, "❌ %v\n", err)
+		}
+	}
+}
+
+// dryRunSource 解析安装来源并试运行
+func dryRunSource(source string, opts InstallOptions) (*DryRunReport, error) {
+	opts.Origin = source
+	zipPath, cleanup, err := resolveInstallSource(source, &opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	zipInfo, err := (&RMMD{}).ValidateModuleZip(zipPath)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(msgOut, "🧪 正在试运行 %s (%s)...\n", zipInfo.ID, zipInfo.Props["version"])
+	report, err := DryRunInstall(zipPath)
+	if err != nil {
+		return nil, err
+	}
+	report.Source = source
+	report.ID = zipInfo.ID
+	report.Version = zipInfo.Props["version"]
+	return report, nil
+}
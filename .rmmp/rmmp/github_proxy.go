@@ -77,11 +77,11 @@ func NewGitHubProxyManager() *GitHubProxyManager {
 func (gpm *GitHubProxyManager) GetProxies() ([]GitHubProxyData, error) {
 	// 检查缓存是否有效
 	if gpm.isCacheValid() {
-		fmt.Println("📦 使用缓存的代理数据")
+		fmt.Fprintln(msgOut, "📦 使用缓存的代理数据")
 		return gpm.loadFromCache()
 	}
 
-	fmt.Println("🔄 缓存已过期或不存在，正在从API获取最新代理数据...")
+	fmt.Fprintln(msgOut, "🔄 缓存已过期或不存在，正在从API获取最新代理数据...")
 	return gpm.fetchFromAPI()
 }
 
@@ -95,17 +95,17 @@ func (gpm *GitHubProxyManager) isCacheValid() bool {
 	// 读取缓存文件
 	cache, err := gpm.readCacheFile()
 	if err != nil {
-		fmt.Printf("⚠️  读取缓存文件失败: %v\n", err)
+		fmt.Fprintf(msgOut, "⚠️  读取缓存文件失败: %v\n", err)
 		return false
 	}
 
 	// 检查缓存时间是否超过10小时
 	if time.Since(cache.CacheTime) > cacheValidDuration {
-		fmt.Printf("⏰ 缓存已过期 (%.1f小时前更新)\n", time.Since(cache.CacheTime).Hours())
+		fmt.Fprintf(msgOut, "⏰ 缓存已过期 (%.1f小时前更新)\n", time.Since(cache.CacheTime).Hours())
 		return false
 	}
 
-	fmt.Printf("✅ 缓存有效 (%.1f小时前更新)\n", time.Since(cache.CacheTime).Hours())
+	fmt.Fprintf(msgOut, "✅ 缓存有效 (%.1f小时前更新)\n", time.Since(cache.CacheTime).Hours())
 	return true
 }
 
@@ -116,7 +116,7 @@ func (gpm *GitHubProxyManager) loadFromCache() ([]GitHubProxyData, error) {
 		return nil, fmt.Errorf("读取缓存失败: %v", err)
 	}
 
-	fmt.Printf("📊 从缓存加载了 %d 个代理地址\n", len(cache.Data))
+	fmt.Fprintf(msgOut, "📊 从缓存加载了 %d 个代理地址\n", len(cache.Data))
 	return cache.Data, nil
 }
 
@@ -151,15 +151,15 @@ func (gpm *GitHubProxyManager) fetchFromAPI() ([]GitHubProxyData, error) {
 		return nil, fmt.Errorf("API返回错误: %s", apiResponse.Message)
 	}
 
-	fmt.Printf("🌐 从API获取了 %d 个代理地址 (服务器更新时间: %s)\n",
+	fmt.Fprintf(msgOut, "🌐 从API获取了 %d 个代理地址 (服务器更新时间: %s)\n",
 		apiResponse.Total, apiResponse.UpdateTime)
 
 	// 保存到缓存
 	if err := gpm.saveToCache(apiResponse); err != nil {
-		fmt.Printf("⚠️  保存缓存失败: %v\n", err)
+		fmt.Fprintf(msgOut, "⚠️  保存缓存失败: %v\n", err)
 		// 即使保存缓存失败，也返回获取到的数据
 	} else {
-		fmt.Println("💾 已保存到缓存文件")
+		fmt.Fprintln(msgOut, "💾 已保存到缓存文件")
 	}
 
 	return apiResponse.Data, nil
@@ -246,27 +246,31 @@ func (gpm *GitHubProxyManager) ListProxies() error {
 		return err
 	}
 
+	if machineOutput() {
+		return printData(proxies)
+	}
+
 	if len(proxies) == 0 {
-		fmt.Println("❌ 没有可用的代理")
+		fmt.Fprintln(msgOut, "❌ 没有可用的代理")
 		return nil
 	}
 
-	fmt.Printf("\n📋 GitHub代理列表 (共 %d 个):\n", len(proxies))
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Printf("%-25s %-15s %-15s %-8s %-8s\n", "代理地址", "服务商", "IP地址", "延迟(ms)", "速度(MB/s)")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Fprintf(msgOut, "\n📋 GitHub代理列表 (共 %d 个):\n", len(proxies))
+	fmt.Fprintln(msgOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Fprintf(msgOut, "%-25s %-15s %-15s %-8s %-8s\n", "代理地址", "服务商", "IP地址", "延迟(ms)", "速度(MB/s)")
+	fmt.Fprintln(msgOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
 	for _, proxy := range proxies {
-		fmt.Printf("%-25s %-15s %-15s %-8d %-8.2f\n",
+		fmt.Fprintf(msgOut, "%-25s %-15s %-15s %-8d %-8.2f\n",
 			proxy.URL, proxy.Server, proxy.IP, proxy.Latency, proxy.Speed)
 	}
 
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Fprintln(msgOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
 	// 显示最佳代理推荐
 	bestProxy, err := gpm.GetBestProxy()
 	if err == nil {
-		fmt.Printf("\n⭐ 推荐代理: %s (延迟: %dms, 速度: %.2fMB/s)\n",
+		fmt.Fprintf(msgOut, "\n⭐ 推荐代理: %s (延迟: %dms, 速度: %.2fMB/s)\n",
 			bestProxy.URL, bestProxy.Latency, bestProxy.Speed)
 	}
 
@@ -276,7 +280,7 @@ func (gpm *GitHubProxyManager) ListProxies() error {
 // ClearCache 清除缓存文件
 func (gpm *GitHubProxyManager) ClearCache() error {
 	if !fileExists(gpm.cacheFile) {
-		fmt.Println("✅ 缓存文件不存在，无需清除")
+		fmt.Fprintln(msgOut, "✅ 缓存文件不存在，无需清除")
 		return nil
 	}
 
@@ -284,7 +288,7 @@ func (gpm *GitHubProxyManager) ClearCache() error {
 		return fmt.Errorf("删除缓存文件失败: %v", err)
 	}
 
-	fmt.Println("🗑️  缓存文件已清除")
+	fmt.Fprintln(msgOut, "🗑️  缓存文件已清除")
 	return nil
 }
 
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 操作结果
+const (
+	OutcomeSuccess   = "success"
+	OutcomeFailed    = "failed"
+	OutcomeCancelled = "cancelled"
+)
+
+// HistoryEntry 操作历史中的一条记录
+type HistoryEntry struct {
+	Time            time.Time `json:"time"`
+	Action          string    `json:"action"` // get, install, upgrade, downgrade, reinstall, uninstall, undo-uninstall, enable, disable
+	ID              string    `json:"id"`
+	FromVersion     string    `json:"fromVersion,omitempty"`
+	FromVersionCode int       `json:"fromVersionCode,omitempty"`
+	ToVersion       string    `json:"toVersion,omitempty"`
+	ToVersionCode   int       `json:"toVersionCode,omitempty"`
+	Source          string    `json:"source,omitempty"` // 仓库、下载链接或本地文件
+	Proxy           string    `json:"proxy,omitempty"`  // 下载时使用的GitHub代理
+	Sha256          string    `json:"sha256,omitempty"`
+	Outcome         string    `json:"outcome"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// getHistoryFilePath 获取操作历史文件路径
+func getHistoryFilePath() string {
+	return filepath.Join(getDataDir(), "history.jsonl")
+}
+
+// recordHistory 追加一条操作历史，写入失败只给出警告
+func recordHistory(entry HistoryEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	data, err := json.Marshal(entry)
+	if err == nil {
+		err = appendHistoryLine(data)
+	}
+	if err != nil {
+		fmt.Fprintf(msgOut, "⚠️  记录操作历史失败: %v\n", err)
+	}
+}
+
+// appendHistoryLine 以追加方式写入一行
+func appendHistoryLine(line []byte) error {
+	if err := os.MkdirAll(getDataDir(), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(getHistoryFilePath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// HistoryFilter 查询操作历史的条件
+type HistoryFilter struct {
+	ModuleID string
+	Since    time.Time
+}
+
+// loadHistory 读取符合条件的操作历史，按时间从旧到新排列
+// 无法解析的行（如写入中断留下的半行）会被跳过
+func loadHistory(filter HistoryFilter) ([]HistoryEntry, error) {
+	file, err := os.Open(getHistoryFilePath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取操作历史失败: %v", err)
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if filter.ModuleID != "" && entry.ID != filter.ModuleID {
+			continue
+		}
+		if entry.Time.Before(filter.Since) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取操作历史失败: %v", err)
+	}
+	return entries, nil
+}
+
+// parseSince 解析 --since 参数: 时长 (30m, 24h, 7d) 或日期 (2006-01-02, RFC3339)
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法识别的时间: %s (例如 24h, 7d, 2006-01-02)", value)
+}
+
+// historyVersions 格式化版本变化
+func historyVersions(entry HistoryEntry) string {
+	switch {
+	case entry.FromVersion != "" && entry.ToVersion != "" && entry.FromVersion != entry.ToVersion:
+		return entry.FromVersion + " → " + entry.ToVersion
+	case entry.ToVersion != "":
+		return entry.ToVersion
+	default:
+		return entry.FromVersion
+	}
+}
+
+// printHistory 打印操作历史
+func printHistory(entries []HistoryEntry) {
+	if len(entries) == 0 {
+		fmt.Fprintln(msgOut, "📋 没有操作记录")
+		return
+	}
+
+	icons := map[string]string{
+		OutcomeSuccess:   "✅",
+		OutcomeFailed:    "❌",
+		OutcomeCancelled: "⏸️ ",
+	}
+
+	fmt.Fprintf(msgOut, "📋 操作历史 (共 %d 条):\n", len(entries))
+	for _, entry := range entries {
+		fmt.Fprintf(msgOut, "%s %s  %-14s %-20s %s\n", icons[entry.Outcome], entry.Time.Local().Format("2006-01-02 15:04:05"),
+			entry.Action, entry.ID, historyVersions(entry))
+
+		var details []string
+		if entry.Source != "" {
+			details = append(details, "来源: "+entry.Source)
+		}
+		if entry.Proxy != "" {
+			details = append(details, "代理: "+entry.Proxy)
+		}
+		if entry.Sha256 != "" {
+			details = append(details, "sha256: "+entry.Sha256[:min(12, len(entry.Sha256))])
+		}
+		if entry.Error != "" {
+			details = append(details, "错误: "+strings.ReplaceAll(entry.Error, "\n", " "))
+		}
+		if len(details) > 0 {
+			fmt.Fprintf(msgOut, "     %s\n", strings.Join(details, "  "))
+		}
+	}
+}
+
+// handleHistoryCommand 处理 history 命令
+func handleHistoryCommand(args []string) {
+	var filter HistoryFilter
+
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		switch name {
+		case "--module", "-m", "--since":
+			if !hasValue {
+				if i+1 >= len(args) {
+					fmt.Fprintf(msgOut, "错误: %s 需要一个参数\n", name)
+					return
+				}
+				i++
+				value = args[i]
+			}
+			if name == "--since" {
+				since, err := parseSince(value, time.Now())
+				if err != nil {
+					fmt.Fprintf(msgOut, "错误: %v\n", err)
+					return
+				}
+				filter.Since = since
+			} else {
+				filter.ModuleID = value
+			}
+		default:
+			fmt.Fprintf(msgOut, "错误: 未知的参数: %s\n", args[i])
+			fmt.Fprintln(msgOut, "用法: rmmp history [--module <模块ID>] [--since <时间>]")
+			return
+		}
+	}
+
+	entries, err := loadHistory(filter)
+	if err != nil {
+		fmt.Fprintf(msgOut, "❌ %v\n", err)
+		return
+	}
+
+	if machineOutput() {
+		if entries == nil {
+			entries = []HistoryEntry{}
+		}
+		if err := printData(entries); err != nil {
+			fmt.Fprintf(msgOut, "❌ %v\n", err)
+		}
+		return
+	}
+	printHistory(entries)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ModuleCapabilities 从模块目录中检测到的功能和状态标记
+type ModuleCapabilities struct {
+	WebUI      bool `json:"webui"`      // webroot/
+	Action     bool `json:"action"`     // action.sh
+	Service    bool `json:"service"`    // service.sh
+	PostFsData bool `json:"postFsData"` // post-fs-data.sh
+	Sepolicy   bool `json:"sepolicy"`   // sepolicy.rule
+	SystemProp bool `json:"systemProp"` // system.prop
+	SkipMount  bool `json:"skipMount"`  // skip_mount
+	Zygisk     bool `json:"zygisk"`     // zygisk/
+	Disable    bool `json:"disable"`    // disable 标记
+	Remove     bool `json:"remove"`     // remove 标记
+	Update     bool `json:"update"`     // update 标记
+}
+
+// ModuleDetails 表示单个模块的详细信息
+type ModuleDetails struct {
+	ModuleInfo
+	Path         string             `json:"path"`
+	Capabilities ModuleCapabilities `json:"capabilities"`
+	DiskUsage    int64              `json:"diskUsage"`
+	Props        map[string]string  `json:"props"`
+}
+
+// detectCapabilities 检测模块目录中的脚本、配置和状态标记
+func (r *RMMD) detectCapabilities(modulePath string) ModuleCapabilities {
+	has := func(name string) bool {
+		return r.fileExists(filepath.Join(modulePath, name))
+	}
+	hasDir := func(name string) bool {
+		return r.dirExists(filepath.Join(modulePath, name))
+	}
+
+	return ModuleCapabilities{
+		WebUI:      hasDir("webroot"),
+		Action:     has("action.sh"),
+		Service:    has("service.sh"),
+		PostFsData: has("post-fs-data.sh"),
+		Sepolicy:   has("sepolicy.rule"),
+		SystemProp: has("system.prop"),
+		SkipMount:  has("skip_mount"),
+		Zygisk:     hasDir("zygisk"),
+		Disable:    has("disable"),
+		Remove:     has("remove"),
+		Update:     has("update"),
+	}
+}
+
+// diskUsage 统计目录占用的空间（字节）
+func diskUsage(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// formatSize 将字节数格式化为易读的大小
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// GetModuleDetails 获取模块的详细信息
+func (r *RMMD) GetModuleDetails(moduleID string) (*ModuleDetails, error) {
+	module, err := r.findModule(moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	modulePath := filepath.Join(modulesDir, module.DirID)
+
+	details := &ModuleDetails{
+		ModuleInfo:   *module,
+		Path:         modulePath,
+		Capabilities: r.detectCapabilities(modulePath),
+		Props:        map[string]string{},
+	}
+
+	if content, err := os.ReadFile(hostPath(filepath.Join(modulePath, "module.prop"))); err == nil {
+		details.Props = ParseModuleProp(string(content)).Map()
+	}
+
+	details.DiskUsage, err = diskUsage(hostPath(modulePath))
+	if err != nil {
+		fmt.Fprintf(msgOut, "⚠️  统计磁盘占用失败: %v\n", err)
+	}
+
+	return details, nil
+}
+
+// PrintModuleDetails 打印模块详细信息
+func (r *RMMD) PrintModuleDetails(moduleID string) error {
+	details, err := r.GetModuleDetails(moduleID)
+	if err != nil {
+		return err
+	}
+
+	if machineOutput() {
+		return printData(details)
+	}
+
+	status := "🔴 已禁用"
+	if details.Enabled {
+		status = "🟢 已启用"
+	}
+
+	fmt.Fprintf(msgOut, "📦 %s (%s)\n", details.Name, details.ID)
+	fmt.Fprintln(msgOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Fprintf(msgOut, "版本: %s (代码: %d)\n", details.Version, details.VersionCode)
+	fmt.Fprintf(msgOut, "作者: %s\n", details.Author)
+	fmt.Fprintf(msgOut, "状态: %s\n", status)
+	fmt.Fprintf(msgOut, "路径: %s\n", details.Path)
+	fmt.Fprintf(msgOut, "占用: %s\n", formatSize(details.DiskUsage))
+	if details.Description != "" {
+		fmt.Fprintf(msgOut, "描述: %s\n", details.Description)
+	}
+
+	caps := details.Capabilities
+	features := []struct {
+		enabled bool
+		name    string
+	}{
+		{caps.WebUI, "WebUI (webroot/)"},
+		{caps.Action, "操作按钮 (action.sh)"},
+		{caps.Service, "开机服务 (service.sh)"},
+		{caps.PostFsData, "post-fs-data.sh"},
+		{caps.Sepolicy, "SELinux规则 (sepolicy.rule)"},
+		{caps.SystemProp, "系统属性 (system.prop)"},
+		{caps.SkipMount, "跳过挂载 (skip_mount)"},
+		{caps.Zygisk, "Zygisk (zygisk/)"},
+	}
+
+	fmt.Fprintln(msgOut, "\n功能:")
+	for _, feature := range features {
+		mark := "  "
+		if feature.enabled {
+			mark = "✅"
+		}
+		fmt.Fprintf(msgOut, "  %s %s\n", mark, feature.name)
+	}
+
+	if caps.Remove || caps.Update {
+		fmt.Fprintln(msgOut, "\n待处理:")
+		if caps.Remove {
+			fmt.Fprintln(msgOut, "  🗑️  重启后删除")
+		}
+		if caps.Update {
+			fmt.Fprintln(msgOut, "  🔄 重启后完成更新")
+		}
+	}
+
+	if len(details.Props) > 0 {
+		fmt.Fprintln(msgOut, "\nmodule.prop:")
+		keys := make([]string, 0, len(details.Props))
+		for key := range details.Props {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(msgOut, "  %s=%s\n", key, details.Props[key])
+		}
+	}
+
+	return nil
+}
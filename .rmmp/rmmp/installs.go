@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// InstallRecord 记录模块通过rmmp安装时的来源
+type InstallRecord struct {
+	ID          string    `json:"id"`
+	VersionCode int       `json:"versionCode"`
+	Source      string    `json:"source,omitempty"` // 仓库，如 username/repo
+	ZipURL      string    `json:"zipUrl,omitempty"`
+	Sha256      string    `json:"sha256"`
+	InstalledAt time.Time `json:"installedAt"`
+}
+
+// getInstallsFilePath 获取安装记录文件路径
+func getInstallsFilePath() string {
+	return filepath.Join(getDataDir(), "installs.json")
+}
+
+// loadInstallRecords 读取安装记录，文件不存在时返回空表
+func loadInstallRecords() (map[string]InstallRecord, error) {
+	records := make(map[string]InstallRecord)
+
+	data, err := os.ReadFile(getInstallsFilePath())
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取安装记录失败: %v", err)
+	}
+
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("解析安装记录失败: %v", err)
+	}
+	return records, nil
+}
+
+// saveInstallRecord 更新一个模块的安装记录
+// 未指定来源时沿用之前记录的仓库，但旧的下载链接不再对应本次安装的版本
+func saveInstallRecord(zipInfo *ModuleZipInfo, opts InstallOptions, sum string) error {
+	records, err := loadInstallRecords()
+	if err != nil {
+		return err
+	}
+
+	record := InstallRecord{
+		ID:          zipInfo.ID,
+		Source:      opts.Source,
+		ZipURL:      opts.ZipURL,
+		Sha256:      sum,
+		InstalledAt: time.Now(),
+	}
+	record.VersionCode, _ = parseIntString(zipInfo.Props["versionCode"])
+	if record.Source == "" {
+		record.Source = records[zipInfo.ID].Source
+	}
+	records[zipInfo.ID] = record
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化安装记录失败: %v", err)
+	}
+	if err := os.MkdirAll(getDataDir(), 0755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %v", err)
+	}
+	return os.WriteFile(getInstallsFilePath(), data, 0644)
+}
+
+// fileSHA256 计算文件的sha256
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// 存在时必须可执行的脚本 (customize.sh 由安装脚本 source，不需要执行权限)
+var moduleScripts = []string{
+	updateBinaryPath,
+	"post-fs-data.sh",
+	"post-mount.sh",
+	"service.sh",
+	"boot-completed.sh",
+	"action.sh",
+	"uninstall.sh",
+}
+
+// LintIssue lint 发现的问题
+type LintIssue struct {
+	Level   string `json:"level"` // error, warning
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// LintReport lint 结果
+type LintReport struct {
+	Target   string      `json:"target"`
+	ModuleID string      `json:"moduleId,omitempty"`
+	Errors   int         `json:"errors"`
+	Warnings int         `json:"warnings"`
+	Issues   []LintIssue `json:"issues"`
+}
+
+// lintFiles 模块目录或zip中的文件
+type lintFiles struct {
+	modes map[string]fs.FileMode // 以 / 分隔的相对路径
+	read  func(name string) ([]byte, error)
+	isZip bool
+}
+
+// add 记录一个问题
+func (lr *LintReport) add(level, file string, line int, format string, args ...interface{}) {
+	lr.Issues = append(lr.Issues, LintIssue{Level: level, File: file, Line: line, Message: fmt.Sprintf(format, args...)})
+	if level == "error" {
+		lr.Errors++
+	} else {
+		lr.Warnings++
+	}
+}
+
+// openLintDir 读取模块目录中的文件列表
+func openLintDir(dir string) (*lintFiles, error) {
+	files := &lintFiles{
+		modes: make(map[string]fs.FileMode),
+		read: func(name string) ([]byte, error) {
+			return os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		},
+	}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files.modes[filepath.ToSlash(rel)] = info.Mode()
+		return nil
+	})
+	return files, err
+}
+
+// openLintZip 读取zip中的文件列表，返回的 close 用于关闭zip
+func openLintZip(zipPath string) (*lintFiles, func(), error) {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("无法打开zip文件: %v", err)
+	}
+
+	entries := make(map[string]*zip.File)
+	files := &lintFiles{
+		modes: make(map[string]fs.FileMode),
+		isZip: true,
+		read: func(name string) ([]byte, error) {
+			f, ok := entries[name]
+			if !ok {
+				return nil, os.ErrNotExist
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return io.ReadAll(rc)
+		},
+	}
+	for _, f := range reader.File {
+		entries[f.Name] = f
+		files.modes[f.Name] = f.Mode()
+	}
+	return files, func() { reader.Close() }, nil
+}
+
+// LintModule 按 Magisk 的规则检查模块目录或zip
+func LintModule(target string) (*LintReport, error) {
+	info, err := os.Stat(target)
+	if err != nil {
+		return nil, fmt.Errorf("文件不存在: %s", target)
+	}
+
+	var files *lintFiles
+	if info.IsDir() {
+		files, err = openLintDir(target)
+	} else {
+		var closeZip func()
+		files, closeZip, err = openLintZip(target)
+		if err == nil {
+			defer closeZip()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report := &LintReport{Target: target, Issues: []LintIssue{}}
+	lintModuleProp(report, files)
+	lintScripts(report, files)
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return report, nil
+}
+
+// lintModuleProp 检查 module.prop
+func lintModuleProp(report *LintReport, files *lintFiles) {
+	const propFile = "module.prop"
+
+	content, err := files.read(propFile)
+	if err != nil {
+		report.add("error", propFile, 0, "缺少 module.prop")
+		return
+	}
+
+	props := ParseProperties(string(content))
+	for _, warning := range props.Warnings {
+		report.add("warning", propFile, warning.Line, "%s", warning.Message)
+	}
+	for _, entry := range props.Entries {
+		if entry.Continued {
+			report.add("warning", propFile, entry.Line, "%s 使用了续行，Root管理器按行读取 module.prop，不会拼接", entry.Key)
+		}
+		if entry.Escaped {
+			report.add("warning", propFile, entry.Line, "%s 包含转义序列，Root管理器会原样显示", entry.Key)
+		}
+	}
+
+	// Root管理器和 rmmp 安装时按行读取，关键字段的值必须与按 Java 格式解析的一致
+	runtime := ParseModuleProp(string(content)).Map()
+	for _, key := range requiredModuleProps {
+		if entry, ok := props.Lookup(key); ok && entry.Value != runtime[key] {
+			report.add("error", propFile, entry.Line, "%s 在Root管理器中读取为 %q 而不是 %q", key, runtime[key], entry.Value)
+		}
+	}
+
+	for _, key := range requiredModuleProps {
+		if entry, ok := props.Lookup(key); !ok || entry.Value == "" {
+			report.add("error", propFile, entry.Line, "缺少必需字段: %s", key)
+		}
+	}
+	for _, key := range []string{"author", "description"} {
+		if entry, ok := props.Lookup(key); !ok || entry.Value == "" {
+			report.add("warning", propFile, entry.Line, "缺少字段: %s", key)
+		}
+	}
+
+	if entry, ok := props.Lookup("id"); ok && entry.Value != "" {
+		report.ModuleID = entry.Value
+		if !moduleIDPattern.MatchString(entry.Value) {
+			report.add("error", propFile, entry.Line, "id %q 不符合 %s", entry.Value, moduleIDPattern.String())
+		}
+	}
+
+	if entry, ok := props.Lookup("versionCode"); ok && entry.Value != "" {
+		if _, err := strconv.Atoi(entry.Value); err != nil {
+			report.add("error", propFile, entry.Line, "versionCode %q 不是整数", entry.Value)
+		}
+	}
+
+	if entry, ok := props.Lookup("updateJson"); ok && entry.Value != "" {
+		u, err := url.Parse(entry.Value)
+		switch {
+		case err != nil:
+			report.add("error", propFile, entry.Line, "updateJson 不是有效的链接: %v", err)
+		case u.Scheme != "https" || u.Host == "":
+			report.add("error", propFile, entry.Line, "updateJson 必须是 https 链接: %s", entry.Value)
+		}
+	}
+}
+
+// lintScripts 检查安装脚本和模块脚本
+func lintScripts(report *LintReport, files *lintFiles) {
+	if files.isZip {
+		if _, ok := files.modes[updateBinaryPath]; !ok {
+			if _, ok := files.modes["customize.sh"]; !ok {
+				report.add("error", updateBinaryPath, 0, "缺少 update-binary 或 customize.sh")
+			}
+		}
+		for name := range files.modes {
+			if isUnsafeZipPath(name) {
+				report.add("error", name, 0, "不安全的路径")
+			}
+		}
+	}
+
+	for _, script := range moduleScripts {
+		mode, ok := files.modes[script]
+		if !ok {
+			continue
+		}
+		if mode.IsDir() {
+			report.add("error", script, 0, "应该是文件而不是目录")
+			continue
+		}
+		if mode.Perm()&0111 == 0 {
+			report.add("error", script, 0, "没有执行权限 (%s)，请 chmod +x", mode.Perm())
+		}
+	}
+}
+
+// handleLintCommand 处理 module lint 命令
+func handleLintCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Fprintln(msgOut, "错误: 请指定模块目录或zip文件")
+		fmt.Fprintln(msgOut, "用法: rmmp module lint <目录|zip>")
+		return
+	}
+
+	report, err := LintModule(args[0])
+	if err != nil {
+		fmt.Fprintf(msgOut, "❌ %v\n", err)
+		return
+	}
+
+	if machineOutput() {
+		if err := printData(report); err != nil {
+			fmt.Fprintf(msgOut, "❌ %v\n", err)
+		}
+		return
+	}
+
+	fmt.Fprintf(msgOut, "🔎 检查 %s\n", report.Target)
+	for _, issue := range report.Issues {
+		icon := "⚠️ "
+		if issue.Level == "error" {
+			icon = "❌"
+		}
+		location := issue.File
+		if issue.Line > 0 {
+			location = fmt.Sprintf("%s:%d", issue.File, issue.Line)
+		}
+		fmt.Fprintf(msgOut, "%s %s: %s\n", icon, location, issue.Message)
+	}
+
+	if len(report.Issues) == 0 {
+		fmt.Fprintln(msgOut, "✅ 未发现问题")
+		return
+	}
+	fmt.Fprintf(msgOut, "\n共 %d 个错误, %d 个警告\n", report.Errors, report.Warnings)
+}
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// 锁文件格式版本
+	lockFormatVersion = 1
+	// 默认锁文件名
+	defaultLockfile = "rmmp.lock.json"
+)
+
+// Lockfile 记录一台设备上的模块集合，用于在其他设备上复现
+type Lockfile struct {
+	FormatVersion int            `json:"formatVersion"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	RootEnv       string         `json:"rootEnv"`
+	Modules       []LockedModule `json:"modules"`
+}
+
+// LockedModule 锁文件中的单个模块
+type LockedModule struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	VersionCode int    `json:"versionCode"`
+	Source      string `json:"source,omitempty"` // 仓库，如 username/repo
+	UpdateJSON  string `json:"updateJson,omitempty"`
+	ZipURL      string `json:"zipUrl,omitempty"`
+	Sha256      string `json:"sha256,omitempty"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// SyncAction 同步时对单个模块执行的操作
+type SyncAction struct {
+	ID     string `json:"id"`
+	Action string `json:"action"` // install, upgrade, downgrade, reinstall, enable, disable, uninstall, undo-uninstall
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+	Status string `json:"status,omitempty"` // done, failed, skipped
+	Reason string `json:"reason,omitempty"`
+}
+
+// syncActionIcons 打印同步计划时使用的图标
+var syncActionIcons = map[string]string{
+	"install":        "➕",
+	"upgrade":        "⬆️ ",
+	"downgrade":      "⬇️ ",
+	"reinstall":      "🔁",
+	"enable":         "🟢",
+	"disable":        "🔴",
+	"uninstall":      "🗑️ ",
+	"undo-uninstall": "↩️ ",
+}
+
+// BuildLockfile 根据已安装的模块生成锁文件
+// 待删除的模块不写入锁文件
+func (r *RMMD) BuildLockfile() (*Lockfile, error) {
+	modules, err := r.ListModules()
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := loadInstallRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &Lockfile{
+		FormatVersion: lockFormatVersion,
+		CreatedAt:     time.Now(),
+		RootEnv:       r.getRootEnvName(),
+		Modules:       []LockedModule{},
+	}
+
+	for _, module := range modules {
+		if module.Remove {
+			continue
+		}
+
+		locked := LockedModule{
+			ID:          module.ID,
+			Name:        module.Name,
+			Version:     module.Version,
+			VersionCode: module.VersionCode,
+			UpdateJSON:  module.UpdateJSON,
+			Enabled:     module.Enabled,
+		}
+
+		// 安装记录只有在版本一致时才能说明当前安装的是哪个zip
+		if record, ok := records[module.ID]; ok {
+			locked.Source = record.Source
+			if record.VersionCode == module.VersionCode {
+				locked.ZipURL = record.ZipURL
+				locked.Sha256 = record.Sha256
+			}
+		}
+
+		lock.Modules = append(lock.Modules, locked)
+	}
+
+	sort.Slice(lock.Modules, func(i, j int) bool {
+		return lock.Modules[i].ID < lock.Modules[j].ID
+	})
+
+	return lock, nil
+}
+
+// readLockfile 读取并检查锁文件
+func readLockfile(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取锁文件失败: %v", err)
+	}
+
+	var lock Lockfile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("解析锁文件失败: %v", err)
+	}
+	if lock.FormatVersion > lockFormatVersion {
+		return nil, fmt.Errorf("锁文件格式版本 %d 高于当前支持的 %d，请先更新rmmp", lock.FormatVersion, lockFormatVersion)
+	}
+
+	seen := make(map[string]bool)
+	for _, module := range lock.Modules {
+		if !moduleIDPattern.MatchString(module.ID) {
+			return nil, fmt.Errorf("锁文件包含无效的模块ID: %s", module.ID)
+		}
+		if seen[module.ID] {
+			return nil, fmt.Errorf("锁文件中模块 %s 重复", module.ID)
+		}
+		seen[module.ID] = true
+	}
+
+	return &lock, nil
+}
+
+// PlanSync 计算使设备与锁文件一致所需的操作
+func (r *RMMD) PlanSync(lock *Lockfile) ([]SyncAction, error) {
+	modules, err := r.ListModules()
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := loadInstallRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	installed := make(map[string]ModuleInfo)
+	for _, module := range modules {
+		installed[module.ID] = module
+	}
+
+	var actions []SyncAction
+	locked := make(map[string]bool)
+	for _, want := range lock.Modules {
+		locked[want.ID] = true
+		to := fmt.Sprintf("%s (%d)", want.Version, want.VersionCode)
+
+		have, ok := installed[want.ID]
+		if !ok {
+			actions = append(actions, SyncAction{ID: want.ID, Action: "install", To: to})
+			if !want.Enabled {
+				actions = append(actions, SyncAction{ID: want.ID, Action: "disable"})
+			}
+			continue
+		}
+
+		from := fmt.Sprintf("%s (%d)", have.Version, have.VersionCode)
+		if have.Remove {
+			actions = append(actions, SyncAction{ID: want.ID, Action: "undo-uninstall"})
+		}
+
+		switch {
+		case have.VersionCode < want.VersionCode:
+			actions = append(actions, SyncAction{ID: want.ID, Action: "upgrade", From: from, To: to})
+		case have.VersionCode > want.VersionCode:
+			actions = append(actions, SyncAction{ID: want.ID, Action: "downgrade", From: from, To: to})
+		default:
+			record, ok := records[want.ID]
+			if want.Sha256 != "" && ok && record.VersionCode == have.VersionCode && record.Sha256 != want.Sha256 {
+				actions = append(actions, SyncAction{ID: want.ID, Action: "reinstall", From: from, To: to, Reason: "sha256 不一致"})
+			}
+		}
+
+		if have.Enabled != want.Enabled {
+			action := "disable"
+			if want.Enabled {
+				action = "enable"
+			}
+			actions = append(actions, SyncAction{ID: want.ID, Action: action})
+		}
+	}
+
+	for _, module := range modules {
+		if !locked[module.ID] && !module.Remove {
+			actions = append(actions, SyncAction{
+				ID:     module.ID,
+				Action: "uninstall",
+				From:   fmt.Sprintf("%s (%d)", module.Version, module.VersionCode),
+			})
+		}
+	}
+
+	return actions, nil
+}
+
+// ApplySync 依次执行同步操作，单个模块失败后跳过该模块的后续操作
+func (r *RMMD) ApplySync(lock *Lockfile, actions []SyncAction) []SyncAction {
+	md := NewModuleDownloader()
+
+	wanted := make(map[string]LockedModule)
+	for _, module := range lock.Modules {
+		wanted[module.ID] = module
+	}
+
+	failed := make(map[string]bool)
+	for i := range actions {
+		action := &actions[i]
+		if failed[action.ID] {
+			action.Status = "skipped"
+			action.Reason = "之前的操作失败"
+			continue
+		}
+
+		fmt.Fprintf(msgOut, "\n%s %s %s\n", syncActionIcons[action.Action], action.Action, action.ID)
+
+		var err error
+		switch action.Action {
+		case "install", "upgrade", "downgrade", "reinstall":
+			err = r.syncInstall(md, wanted[action.ID])
+		default:
+			_, err = r.changeModuleState(action.ID, action.Action)
+		}
+
+		if err != nil {
+			failed[action.ID] = true
+			action.Status = "failed"
+			action.Reason = err.Error()
+			fmt.Fprintf(msgOut, "❌ %v\n", err)
+			continue
+		}
+		action.Status = "done"
+	}
+
+	return actions
+}
+
+// syncInstall 获取锁文件指定版本的zip并安装
+func (r *RMMD) syncInstall(md *ModuleDownloader, want LockedModule) error {
+	md.lastProxy = ""
+	zipPath, err := fetchLockedZip(md, want)
+	if err != nil {
+		return err
+	}
+
+	// 目标状态由锁文件决定，文件冲突在源设备上同样存在，不再中断
+	return r.InstallModule(zipPath, InstallOptions{
+		ConflictPolicy: ConflictContinue,
+		Source:         want.Source,
+		ZipURL:         want.ZipURL,
+		Proxy:          md.lastProxy,
+	})
+}
+
+// fetchLockedZip 按优先级获取锁定版本的zip: 本地保存的安装包、锁文件中的下载链接、仓库的update.json
+func fetchLockedZip(md *ModuleDownloader, want LockedModule) (string, error) {
+	if want.Sha256 != "" {
+		archive := filepath.Join(getRestoreDir(want.ID), archiveName(want.Sha256))
+		if fileExists(archive) {
+			if err := verifySHA256(archive, want.Sha256); err == nil {
+				fmt.Fprintf(msgOut, "📦 使用本地保存的安装包: %s\n", archive)
+				return archive, nil
+			}
+		}
+	}
+
+	updateInfo := &UpdateInfo{
+		Version:     want.Version,
+		VersionCode: want.VersionCode,
+		ZipURL:      want.ZipURL,
+	}
+
+	if updateInfo.ZipURL == "" {
+		var err error
+		switch {
+		case want.Source != "":
+			updateInfo, err = md.downloadUpdateJSON(md.normalizeRepoName(want.Source))
+		case want.UpdateJSON != "":
+			updateInfo, err = md.fetchUpdateJSON(want.UpdateJSON)
+		default:
+			return "", fmt.Errorf("锁文件没有记录模块 %s 的来源，无法下载", want.ID)
+		}
+		if err != nil {
+			return "", fmt.Errorf("获取更新信息失败: %v", err)
+		}
+		if updateInfo.VersionCode != want.VersionCode {
+			return "", fmt.Errorf("远程版本代码为 %d，与锁定的 %d 不一致", updateInfo.VersionCode, want.VersionCode)
+		}
+	}
+
+	zipPath, err := md.downloadModule(updateInfo)
+	if err != nil {
+		return "", fmt.Errorf("下载模块失败: %v", err)
+	}
+	if err := verifySHA256(zipPath, want.Sha256); err != nil {
+		return "", err
+	}
+	return zipPath, nil
+}
+
+// verifySHA256 校验文件的sha256，expected 为空时不校验
+func verifySHA256(path, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return fmt.Errorf("计算sha256失败: %v", err)
+	}
+	if !strings.EqualFold(sum, expected) {
+		return fmt.Errorf("sha256 不匹配: 期望 %s，实际 %s", expected, sum)
+	}
+	return nil
+}
+
+// printSyncActions 打印同步计划或结果
+func printSyncActions(actions []SyncAction) {
+	for _, action := range actions {
+		line := fmt.Sprintf("  %s %-15s %s", syncActionIcons[action.Action], action.Action, action.ID)
+		switch {
+		case action.From != "" && action.To != "":
+			line += fmt.Sprintf("  %s → %s", action.From, action.To)
+		case action.To != "":
+			line += "  " + action.To
+		case action.From != "":
+			line += "  " + action.From
+		}
+		if action.Status != "" {
+			line += "  [" + action.Status + "]"
+		}
+		if action.Reason != "" {
+			line += "  " + action.Reason
+		}
+		fmt.Fprintln(msgOut, line)
+	}
+}
+
+// askYesNo 询问用户是否继续，默认为否
+func askYesNo(prompt string) bool {
+	fmt.Fprintf(msgOut, "❓ %s [y/N]: ", prompt)
+
+	reader := bufio.NewReader(os.Stdin)
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		fmt.Fprintf(msgOut, "读取输入失败: %v\n", err)
+		return false
+	}
+
+	input = strings.TrimSpace(strings.ToLower(input))
+	return input == "y" || input == "yes"
+}
+
+// handleLockExportCommand 处理 export 命令
+func handleLockExportCommand(args []string) {
+	path := defaultLockfile
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	rmmd := NewRMMD()
+	lock, err := rmmd.BuildLockfile()
+	if err != nil {
+		fmt.Fprintf(msgOut, "❌ 生成锁文件失败: %v\n", err)
+		return
+	}
+
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		fmt.Fprintf(msgOut, "❌ 序列化锁文件失败: %v\n", err)
+		return
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		fmt.Fprintf(msgOut, "❌ 写入锁文件失败: %v\n", err)
+		return
+	}
+
+	if machineOutput() {
+		if err := printData(lock); err != nil {
+			fmt.Fprintf(msgOut, "❌ %v\n", err)
+		}
+		return
+	}
+
+	fmt.Fprintf(msgOut, "✅ 已导出 %d 个模块到 %s\n", len(lock.Modules), path)
+	for _, module := range lock.Modules {
+		if module.Sha256 == "" {
+			fmt.Fprintf(msgOut, "⚠️  模块 %s 不是通过rmmp安装的，锁文件中没有sha256\n", module.ID)
+		}
+	}
+}
+
+// handleSyncCommand 处理 sync 命令
+func handleSyncCommand(args []string) {
+	var path string
+	dryRun, yes := false, false
+	for _, arg := range args {
+		switch arg {
+		case "--dry-run", "-n":
+			dryRun = true
+		case "--yes", "-y":
+			yes = true
+		default:
+			path = arg
+		}
+	}
+
+	if path == "" {
+		fmt.Fprintln(msgOut, "错误: 请指定锁文件")
+		fmt.Fprintln(msgOut, "用法: rmmp sync <锁文件> [--dry-run] [-y]")
+		return
+	}
+
+	lock, err := readLockfile(path)
+	if err != nil {
+		fmt.Fprintf(msgOut, "❌ %v\n", err)
+		return
+	}
+
+	rmmd := NewRMMD()
+	actions, err := rmmd.PlanSync(lock)
+	if err != nil {
+		fmt.Fprintf(msgOut, "❌ 计算差异失败: %v\n", err)
+		return
+	}
+
+	if len(actions) == 0 {
+		fmt.Fprintln(msgOut, "✅ 设备上的模块已与锁文件一致")
+		if machineOutput() {
+			if err := printData([]SyncAction{}); err != nil {
+				fmt.Fprintf(msgOut, "❌ %v\n", err)
+			}
+		}
+		return
+	}
+
+	fmt.Fprintf(msgOut, "📋 需要执行 %d 个操作:\n", len(actions))
+	printSyncActions(actions)
+
+	if dryRun {
+		if machineOutput() {
+			if err := printData(actions); err != nil {
+				fmt.Fprintf(msgOut, "❌ %v\n", err)
+			}
+		}
+		return
+	}
+
+	if !yes && !askYesNo("是否继续?") {
+		fmt.Fprintln(msgOut, "⏸️  已取消")
+		return
+	}
+
+	actions = rmmd.ApplySync(lock, actions)
+
+	if machineOutput() {
+		if err := printData(actions); err != nil {
+			fmt.Fprintf(msgOut, "❌ %v\n", err)
+		}
+		return
+	}
+
+	fmt.Fprintln(msgOut, "\n📊 同步结果:")
+	printSyncActions(actions)
+	fmt.Fprintln(msgOut, "🔄 需要重启设备后生效")
+}
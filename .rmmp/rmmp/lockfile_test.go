@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestPlanSync(t *testing.T) {
+	installed := []ModuleInfo{
+		{ID: "same", Version: "v1", VersionCode: 1, Enabled: true},
+		{ID: "old", Version: "v1", VersionCode: 1, Enabled: true},
+		{ID: "new", Version: "v3", VersionCode: 3, Enabled: true},
+		{ID: "off", Version: "v1", VersionCode: 1, Enabled: false},
+		{ID: "removed", Version: "v1", VersionCode: 1, Enabled: true, Remove: true},
+		{ID: "extra", Version: "v2", VersionCode: 2, Enabled: true},
+		{ID: "gone", Version: "v1", VersionCode: 1, Enabled: true, Remove: true},
+		{ID: "changed", Version: "v1", VersionCode: 1, Enabled: true},
+	}
+
+	tests := []struct {
+		name   string
+		locked []LockedModule
+		want   []SyncAction
+	}{
+		{
+			name: "in sync",
+			locked: []LockedModule{
+				{ID: "same", Version: "v1", VersionCode: 1, Enabled: true},
+				{ID: "old", Version: "v1", VersionCode: 1, Enabled: true},
+				{ID: "new", Version: "v3", VersionCode: 3, Enabled: true},
+				{ID: "off", Version: "v1", VersionCode: 1},
+				{ID: "extra", Version: "v2", VersionCode: 2, Enabled: true},
+				{ID: "changed", Version: "v1", VersionCode: 1, Enabled: true},
+			},
+		},
+		{
+			name: "version and state changes",
+			locked: []LockedModule{
+				{ID: "same", Version: "v1", VersionCode: 1, Enabled: false},
+				{ID: "old", Version: "v2", VersionCode: 2, Enabled: true},
+				{ID: "new", Version: "v2", VersionCode: 2, Enabled: true},
+				{ID: "off", Version: "v1", VersionCode: 1, Enabled: true},
+				{ID: "removed", Version: "v1", VersionCode: 1, Enabled: true},
+				{ID: "missing", Version: "v1", VersionCode: 1, Enabled: false},
+				{ID: "extra", Version: "v2", VersionCode: 2, Enabled: true},
+				{ID: "changed", Version: "v1", VersionCode: 1, Enabled: true, Sha256: "bbbb"},
+			},
+			want: []SyncAction{
+				{ID: "same", Action: "disable"},
+				{ID: "old", Action: "upgrade", From: "v1 (1)", To: "v2 (2)"},
+				{ID: "new", Action: "downgrade", From: "v3 (3)", To: "v2 (2)"},
+				{ID: "off", Action: "enable"},
+				{ID: "removed", Action: "undo-uninstall"},
+				{ID: "missing", Action: "install", To: "v1 (1)"},
+				{ID: "missing", Action: "disable"},
+				{ID: "changed", Action: "reinstall", From: "v1 (1)", To: "v1 (1)", Reason: "sha256 不一致"},
+			},
+		},
+		{
+			name: "unlocked modules uninstalled",
+			locked: []LockedModule{
+				{ID: "same", Version: "v1", VersionCode: 1, Enabled: true},
+			},
+			want: []SyncAction{
+				{ID: "changed", Action: "uninstall", From: "v1 (1)"},
+				{ID: "extra", Action: "uninstall", From: "v2 (2)"},
+				{ID: "new", Action: "uninstall", From: "v3 (3)"},
+				{ID: "off", Action: "uninstall", From: "v1 (1)"},
+				{ID: "old", Action: "uninstall", From: "v1 (1)"},
+			},
+		},
+	}
+
+	t.Setenv("HOME", t.TempDir())
+	records := map[string]InstallRecord{
+		"changed": {ID: "changed", VersionCode: 1, Sha256: "aaaa"},
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(getDataDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(getInstallsFilePath(), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRMMDWithBackend(NewFakeBackend(installed...))
+
+			got, err := r.PlanSync(&Lockfile{Modules: tt.locked})
+			if err != nil {
+				t.Fatalf("PlanSync: %v", err)
+			}
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ModuleInfo 表示模块信息的结构
+// 三种Root后端返回的模块信息都会经过 normalizeModule 统一处理
+type ModuleInfo struct {
+	ID          string `json:"id"`
+	UpdateJSON  string `json:"updateJson"`
+	VersionCode int    `json:"versionCode"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	Update      bool   `json:"update"`   // 有已暂存、重启后生效的更新（与Root管理器的含义一致）
+	Outdated    bool   `json:"outdated"` // 有可用更新，由 ApplyUpdateStatus 设置
+	Name        string `json:"name"`
+	Web         bool   `json:"web"`
+	Version     string `json:"version"`
+	Author      string `json:"author"`
+	DirID       string `json:"dir_id"`
+	Action      bool   `json:"action"`
+	Remove      bool   `json:"remove"`
+}
+
+// UnmarshalJSON 宽松地解析模块信息
+// ksud/apd 输出的布尔值和版本代码都是字符串（"true"、"123"），
+// 这里同时接受字符串和原生的JSON布尔值/数字
+func (m *ModuleInfo) UnmarshalJSON(data []byte) error {
+	type plainModuleInfo ModuleInfo
+	var raw struct {
+		plainModuleInfo
+		VersionCode json.RawMessage `json:"versionCode"`
+		Enabled     json.RawMessage `json:"enabled"`
+		Update      json.RawMessage `json:"update"`
+		Web         json.RawMessage `json:"web"`
+		Action      json.RawMessage `json:"action"`
+		Remove      json.RawMessage `json:"remove"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*m = ModuleInfo(raw.plainModuleInfo)
+
+	// 单个字段无效时只给出警告，不影响整个模块列表
+	var err error
+	if m.VersionCode, err = parseLooseInt(raw.VersionCode); err != nil {
+		fmt.Fprintf(msgOut, "⚠️  模块 %s 的 versionCode 无效: %v\n", m.ID, err)
+	}
+
+	bools := []struct {
+		name  string
+		raw   json.RawMessage
+		value *bool
+	}{
+		{"enabled", raw.Enabled, &m.Enabled},
+		{"update", raw.Update, &m.Update},
+		{"web", raw.Web, &m.Web},
+		{"action", raw.Action, &m.Action},
+		{"remove", raw.Remove, &m.Remove},
+	}
+	for _, field := range bools {
+		if *field.value, err = parseLooseBool(field.raw); err != nil {
+			fmt.Fprintf(msgOut, "⚠️  模块 %s 的 %s 无效: %v\n", m.ID, field.name, err)
+		}
+	}
+
+	return nil
+}
+
+// parseLooseBool 解析JSON布尔值或 "true"/"false"/"1"/"0" 字符串，缺省为false
+func parseLooseBool(raw json.RawMessage) (bool, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return false, nil
+	}
+
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return parseBoolString(s)
+	}
+
+	var n int
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n != 0, nil
+	}
+
+	return false, fmt.Errorf("无法解析为布尔值: %s", raw)
+}
+
+// parseBoolString 解析字符串形式的布尔值，空字符串为false
+func parseBoolString(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "false", "0", "no", "off":
+		return false, nil
+	case "true", "1", "yes", "on":
+		return true, nil
+	}
+	return false, fmt.Errorf("无法解析为布尔值: %q", s)
+}
+
+// parseLooseInt 解析JSON数字或数字字符串，缺省为0
+func parseLooseInt(raw json.RawMessage) (int, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, nil
+	}
+
+	var n int
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return parseIntString(s)
+	}
+
+	return 0, fmt.Errorf("无法解析为整数: %s", raw)
+}
+
+// parseIntString 解析字符串形式的整数，空字符串为0
+func parseIntString(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// moduleFromProps 根据 module.prop 的内容和检测到的功能构造模块信息
+// 与Magisk一致，模块ID取模块目录名
+func moduleFromProps(dirID string, props map[string]string, caps ModuleCapabilities) ModuleInfo {
+	versionCode, err := parseIntString(props["versionCode"])
+	if err != nil {
+		fmt.Fprintf(msgOut, "⚠️  模块 %s 的 versionCode 无效: %s\n", dirID, props["versionCode"])
+	}
+
+	return ModuleInfo{
+		ID:          dirID,
+		UpdateJSON:  props["updateJson"],
+		VersionCode: versionCode,
+		Description: props["description"],
+		Enabled:     !caps.Disable,
+		Name:        props["name"],
+		Web:         caps.WebUI,
+		Version:     props["version"],
+		Author:      props["author"],
+		DirID:       dirID,
+		Action:      caps.Action,
+		Remove:      caps.Remove,
+		Update:      caps.Update,
+	}
+}
+
+// normalizeModule 统一不同Root后端返回的模块信息
+func normalizeModule(m *ModuleInfo) {
+	m.ID = strings.TrimSpace(m.ID)
+	m.DirID = strings.TrimSpace(m.DirID)
+	m.Name = strings.TrimSpace(m.Name)
+	m.Version = strings.TrimSpace(m.Version)
+	m.Author = strings.TrimSpace(m.Author)
+	m.Description = strings.TrimSpace(m.Description)
+	m.UpdateJSON = strings.TrimSpace(m.UpdateJSON)
+
+	// 模块目录名即模块ID
+	if m.ID == "" {
+		m.ID = m.DirID
+	}
+	if m.DirID == "" {
+		m.DirID = m.ID
+	}
+	if m.Name == "" {
+		m.Name = m.ID
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListModulesKeepsPendingUpdate(t *testing.T) {
+	root := withSysroot(t)
+	if err := os.MkdirAll(filepath.Join(root, "modules_update", "staged"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// ksud/apd 输出的 update 是字符串
+	var reported ModuleInfo
+	if err := json.Unmarshal([]byte(`{"id":"reported","update":"true"}`), &reported); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRMMDWithBackend(NewFakeBackend(
+		reported,
+		ModuleInfo{ID: "staged"},
+		ModuleInfo{ID: "plain"},
+	))
+	modules, err := r.ListModules()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]bool{"reported": true, "staged": true, "plain": false}
+	for _, module := range modules {
+		if module.Update != want[module.ID] {
+			t.Errorf("%s update = %v, want %v", module.ID, module.Update, want[module.ID])
+		}
+		if module.Outdated {
+			t.Errorf("%s outdated before checking updates", module.ID)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 模块列表的排序方式
+var listSortKeys = []string{"name", "id", "version", "size", "installed-at"}
+
+// ListOptions module list 的过滤、排序和显示选项
+type ListOptions struct {
+	Enabled   bool
+	Disabled  bool
+	Author    string
+	HasUpdate bool
+	HasWebUI  bool
+	// Query 不区分大小写匹配模块ID、名称和描述
+	Query string
+	// Sort 排序方式，为空时保持目录顺序
+	Sort    string
+	Compact bool
+}
+
+// filtered 是否设置了过滤条件
+func (o ListOptions) filtered() bool {
+	return o.Enabled || o.Disabled || o.Author != "" || o.HasUpdate || o.HasWebUI || o.Query != ""
+}
+
+// parseListArgs 解析 module list 的参数
+func parseListArgs(args []string) (ListOptions, error) {
+	var opts ListOptions
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		switch name {
+		case "--enabled":
+			opts.Enabled = true
+		case "--disabled":
+			opts.Disabled = true
+		case "--has-update":
+			opts.HasUpdate = true
+		case "--has-webui":
+			opts.HasWebUI = true
+		case "--compact", "-c":
+			opts.Compact = true
+		case "--author", "--sort":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return opts, fmt.Errorf("%s 需要一个参数", name)
+				}
+				i++
+				value = args[i]
+			}
+			if name == "--author" {
+				opts.Author = value
+				continue
+			}
+			valid := false
+			for _, key := range listSortKeys {
+				valid = valid || key == value
+			}
+			if !valid {
+				return opts, fmt.Errorf("未知的排序方式: %s (可选: %s)", value, strings.Join(listSortKeys, ", "))
+			}
+			opts.Sort = value
+		default:
+			if strings.HasPrefix(args[i], "-") {
+				return opts, fmt.Errorf("未知的参数: %s", args[i])
+			}
+			if opts.Query != "" {
+				return opts, fmt.Errorf("只能指定一个搜索关键词")
+			}
+			opts.Query = args[i]
+		}
+	}
+
+	if opts.Enabled && opts.Disabled {
+		return opts, fmt.Errorf("--enabled 和 --disabled 不能同时使用")
+	}
+	return opts, nil
+}
+
+// filterModules 按条件过滤模块，更新状态需要先调用 ApplyUpdateStatus
+func filterModules(modules []ModuleInfo, opts ListOptions) []ModuleInfo {
+	query := strings.ToLower(opts.Query)
+	author := strings.ToLower(opts.Author)
+
+	filtered := []ModuleInfo{}
+	for _, module := range modules {
+		switch {
+		case opts.Enabled && !module.Enabled,
+			opts.Disabled && module.Enabled,
+			opts.HasUpdate && !module.Outdated,
+			opts.HasWebUI && !module.Web,
+			author != "" && !strings.Contains(strings.ToLower(module.Author), author):
+			continue
+		}
+		if query != "" &&
+			!strings.Contains(strings.ToLower(module.ID), query) &&
+			!strings.Contains(strings.ToLower(module.Name), query) &&
+			!strings.Contains(strings.ToLower(module.Description), query) {
+			continue
+		}
+		filtered = append(filtered, module)
+	}
+	return filtered
+}
+
+// moduleDir 返回模块目录（设备路径）
+func moduleDir(module ModuleInfo) string {
+	dirID := module.DirID
+	if dirID == "" {
+		dirID = module.ID
+	}
+	return filepath.Join(modulesDir, dirID)
+}
+
+// moduleSizes 统计模块目录占用的空间
+func moduleSizes(modules []ModuleInfo) map[string]int64 {
+	sizes := make(map[string]int64)
+	for _, module := range modules {
+		sizes[module.ID], _ = diskUsage(hostPath(moduleDir(module)))
+	}
+	return sizes
+}
+
+// moduleInstallTimes 获取模块的安装时间
+// 优先使用rmmp的安装记录，没有记录时使用模块目录的修改时间
+func moduleInstallTimes(modules []ModuleInfo) map[string]time.Time {
+	records, err := loadInstallRecords()
+	if err != nil {
+		fmt.Fprintf(msgOut, "⚠️  %v\n", err)
+	}
+
+	times := make(map[string]time.Time)
+	for _, module := range modules {
+		if record, ok := records[module.ID]; ok {
+			times[module.ID] = record.InstalledAt
+		} else if info, err := os.Stat(hostPath(moduleDir(module))); err == nil {
+			times[module.ID] = info.ModTime()
+		}
+	}
+	return times
+}
+
+// sortModules 排序模块列表，按 size 排序时需要传入 moduleSizes 的结果
+// name、id、version 升序；size 从大到小；installed-at 从新到旧
+func sortModules(modules []ModuleInfo, key string, sizes map[string]int64) {
+	var less func(a, b ModuleInfo) bool
+	switch key {
+	case "name":
+		less = func(a, b ModuleInfo) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) }
+	case "id":
+		less = func(a, b ModuleInfo) bool { return a.ID < b.ID }
+	case "version":
+		less = func(a, b ModuleInfo) bool { return a.VersionCode < b.VersionCode }
+	case "size":
+		less = func(a, b ModuleInfo) bool { return sizes[a.ID] > sizes[b.ID] }
+	case "installed-at":
+		times := moduleInstallTimes(modules)
+		less = func(a, b ModuleInfo) bool { return times[a.ID].After(times[b.ID]) }
+	default:
+		return
+	}
+	sort.SliceStable(modules, func(i, j int) bool { return less(modules[i], modules[j]) })
+}
+
+// printCompactModuleList 每个模块一行，sizes 不为空时显示占用空间
+func printCompactModuleList(modules []ModuleInfo, checked map[string]bool, sizes map[string]int64) {
+	for _, module := range modules {
+		status := "🔴"
+		if module.Enabled {
+			status = "🟢"
+		}
+
+		var flags []string
+		if module.Outdated {
+			flags = append(flags, "🔄")
+		} else if module.UpdateJSON != "" && !checked[module.ID] {
+			flags = append(flags, "❔")
+		}
+		if module.Update {
+			flags = append(flags, "⏳")
+		}
+		if module.Web {
+			flags = append(flags, "🌐")
+		}
+		if module.Remove {
+			flags = append(flags, "🗑️")
+		}
+
+		line := fmt.Sprintf("%s %-28s %-14s ", status, module.ID, module.Version)
+		if sizes != nil {
+			line += fmt.Sprintf("%10s ", formatSize(sizes[module.ID]))
+		}
+		line += module.Name
+		if len(flags) > 0 {
+			line += " " + strings.Join(flags, " ")
+		}
+		fmt.Fprintln(msgOut, line)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	// 输出格式
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+
+	// 指定输出格式的环境变量
+	outputEnv = "RMMP_OUTPUT"
+)
+
+// outputFormat 当前的输出格式
+var outputFormat = outputTable
+
+// dataOut 机器可读数据的输出目标
+var dataOut io.Writer = os.Stdout
+
+// msgOut 面向用户的状态信息的输出目标，包括安装脚本等子进程的输出
+// json/yaml 模式下切换为 os.Stderr，stdout 只包含数据
+var msgOut io.Writer = os.Stdout
+
+// setOutputFormat 设置输出格式
+func setOutputFormat(format string) error {
+	switch strings.ToLower(format) {
+	case "", outputTable:
+		outputFormat = outputTable
+	case outputJSON:
+		outputFormat = outputJSON
+	case outputYAML, "yml":
+		outputFormat = outputYAML
+	default:
+		return fmt.Errorf("不支持的输出格式: %s (可选: json, yaml, table)", format)
+	}
+
+	msgOut = os.Stdout
+	if machineOutput() {
+		msgOut = os.Stderr
+	}
+	return nil
+}
+
+// machineOutput 是否使用机器可读的输出格式
+func machineOutput() bool {
+	return outputFormat == outputJSON || outputFormat == outputYAML
+}
+
+// printData 以当前输出格式将数据写入stdout
+// 字段名与结构体的json标签一致
+func printData(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("序列化输出失败: %v", err)
+	}
+
+	switch outputFormat {
+	case outputYAML:
+		out, err := jsonToYAML(data)
+		if err != nil {
+			return fmt.Errorf("序列化输出失败: %v", err)
+		}
+		_, err = io.WriteString(dataOut, out)
+		return err
+	default:
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, data, "", "  "); err != nil {
+			return fmt.Errorf("序列化输出失败: %v", err)
+		}
+		buf.WriteByte('\n')
+		_, err = dataOut.Write(buf.Bytes())
+		return err
+	}
+}
+
+// yamlNode JSON解码后保留字段顺序的节点
+type yamlNode struct {
+	kind   byte // 'm' 对象, 'l' 数组, 's' 标量
+	keys   []string
+	items  []*yamlNode
+	scalar string
+}
+
+// jsonToYAML 将JSON转换为YAML，保持字段顺序不变
+func jsonToYAML(data []byte) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	node, err := decodeYAMLNode(dec)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	switch {
+	case node.kind == 's':
+		b.WriteString(node.scalar + "\n")
+	case len(node.items) == 0 && node.kind == 'm':
+		b.WriteString("{}\n")
+	case len(node.items) == 0:
+		b.WriteString("[]\n")
+	default:
+		writeYAMLNode(&b, node, 0)
+	}
+	return b.String(), nil
+}
+
+// decodeYAMLNode 从JSON token流中读取一个节点
+func decodeYAMLNode(dec *json.Decoder) (*yamlNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		node := &yamlNode{kind: 'l'}
+		if t == '{' {
+			node.kind = 'm'
+		}
+		for dec.More() {
+			if node.kind == 'm' {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.keys = append(node.keys, yamlScalar(keyTok.(string)))
+			}
+			child, err := decodeYAMLNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.items = append(node.items, child)
+		}
+		// 读取结束符
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return &yamlNode{kind: 's', scalar: yamlScalar(t)}, nil
+	case json.Number:
+		return &yamlNode{kind: 's', scalar: t.String()}, nil
+	case bool:
+		return &yamlNode{kind: 's', scalar: fmt.Sprintf("%t", t)}, nil
+	default:
+		return &yamlNode{kind: 's', scalar: "null"}, nil
+	}
+}
+
+// writeYAMLNode 以块格式输出对象或数组
+func writeYAMLNode(b *strings.Builder, node *yamlNode, indent int) {
+	pad := strings.Repeat(" ", indent)
+
+	for i, child := range node.items {
+		prefix := pad + "- "
+		if node.kind == 'm' {
+			prefix = pad + node.keys[i] + ":"
+		}
+
+		switch {
+		case child.kind == 's':
+			if node.kind == 'm' {
+				prefix += " "
+			}
+			b.WriteString(prefix + child.scalar + "\n")
+		case len(child.items) == 0:
+			empty := " []"
+			if child.kind == 'm' {
+				empty = " {}"
+			}
+			b.WriteString(strings.TrimRight(prefix, " ") + empty + "\n")
+		case node.kind == 'm':
+			b.WriteString(prefix + "\n")
+			writeYAMLNode(b, child, indent+2)
+		default:
+			// 数组中的对象/数组：第一行紧跟在 "- " 之后
+			var nested strings.Builder
+			writeYAMLNode(&nested, child, indent+2)
+			b.WriteString(prefix + strings.TrimPrefix(nested.String(), pad+"  "))
+		}
+	}
+}
+
+// yamlPlainScalar 无需加引号的YAML字符串
+var yamlPlainScalar = regexp.MustCompile(`^[A-Za-z_/][A-Za-z0-9_./@+-]*( [A-Za-z0-9_./@+()-]+)*$`)
+
+// yamlScalar 将字符串转换为YAML标量，必要时使用双引号
+func yamlScalar(s string) string {
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "y", "n":
+		return jsonQuote(s)
+	}
+	if yamlPlainScalar.MatchString(s) {
+		return s
+	}
+	return jsonQuote(s)
+}
+
+// jsonQuote 使用JSON字符串语法（YAML双引号标量的子集）加引号
+func jsonQuote(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return fmt.Sprintf("%q", s)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,70 @@
+package main
+
+import "testing"
+
+func TestJSONToYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{
+			name: "scalar",
+			json: `"hello"`,
+			want: "hello\n",
+		},
+		{
+			name: "empty object",
+			json: `{}`,
+			want: "{}\n",
+		},
+		{
+			name: "empty array",
+			json: `[]`,
+			want: "[]\n",
+		},
+		{
+			name: "key order kept",
+			json: `{"z":1,"a":true,"m":null}`,
+			want: "z: 1\na: true\nm: null\n",
+		},
+		{
+			name: "strings quoted when needed",
+			json: `{"id":"foo_bar","yes":"yes","num":"120","desc":"a: b","path":"/data/adb"}`,
+			want: "id: foo_bar\n\"yes\": \"yes\"\nnum: \"120\"\ndesc: \"a: b\"\npath: /data/adb\n",
+		},
+		{
+			name: "nested",
+			json: `{"module":{"id":"foo","tags":["a","b"],"empty":{}}}`,
+			want: "module:\n  id: foo\n  tags:\n    - a\n    - b\n  empty: {}\n",
+		},
+		{
+			name: "objects in list",
+			json: `[{"id":"foo","versionCode":1},{"id":"bar","versionCode":2}]`,
+			want: "- id: foo\n  versionCode: 1\n- id: bar\n  versionCode: 2\n",
+		},
+		{
+			name: "nested lists",
+			json: `[[1,2],[]]`,
+			want: "- - 1\n  - 2\n- []\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jsonToYAML([]byte(tt.json))
+			if err != nil {
+				t.Fatalf("jsonToYAML: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONToYAMLInvalid(t *testing.T) {
+	if _, err := jsonToYAML([]byte(`{"id":`)); err == nil {
+		t.Error("expected error for truncated JSON")
+	}
+}
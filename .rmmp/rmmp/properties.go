@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PropEntry properties文件中的一个键值对
+type PropEntry struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Line   int    `json:"line"`   // 所在行（从1开始，续行时为第一行）
+	Column int    `json:"column"` // 键所在列（从1开始）
+	// Continued 使用了 \ 续行
+	Continued bool `json:"continued,omitempty"`
+	// Escaped 键或值中包含转义序列
+	Escaped bool `json:"escaped,omitempty"`
+}
+
+// PropWarning 解析时发现的问题
+type PropWarning struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// Properties 解析后的properties文件，保留每个键值对的位置
+type Properties struct {
+	Entries  []PropEntry   `json:"entries"`
+	Warnings []PropWarning `json:"warnings,omitempty"`
+}
+
+// ParseProperties 按 Java properties 格式解析文件内容
+// 支持 BOM、CRLF/CR 换行、# 和 ! 注释、= : 或空白分隔、\ 续行以及 \t \n \uXXXX 等转义；
+// 重复的键以最后一次为准并给出警告
+func ParseProperties(content string) *Properties {
+	props := &Properties{}
+	content = strings.TrimPrefix(content, "\uFEFF")
+
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\r", "\n")
+	lines := strings.Split(content, "\n")
+
+	seen := make(map[string]int)
+	for i := 0; i < len(lines); i++ {
+		start := i + 1
+		line := strings.TrimLeft(lines[i], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		column := len(lines[i]) - len(line) + 1
+
+		// 以奇数个反斜杠结尾时与下一行拼接
+		continued := false
+		for endsWithContinuation(line) {
+			continued = true
+			line = line[:len(line)-1]
+			if i+1 >= len(lines) {
+				props.warn(start, "文件末尾的续行符 \\ 没有下一行")
+				break
+			}
+			i++
+			line += strings.TrimLeft(lines[i], " \t\f")
+		}
+
+		rawKey, rawValue, hasSeparator := splitPropLine(line)
+		if !hasSeparator {
+			props.warn(start, fmt.Sprintf("缺少分隔符 =: %s", line))
+		}
+
+		key, keyEscaped := props.unescape(start, rawKey)
+		value, valueEscaped := props.unescape(start, rawValue)
+		value = strings.TrimRight(value, " \t\f")
+		if key == "" {
+			props.warn(start, "键为空")
+			continue
+		}
+
+		if prev, ok := seen[key]; ok {
+			props.warn(start, fmt.Sprintf("重复的键 %s (第 %d 行已定义)，以本行为准", key, props.Entries[prev].Line))
+		}
+		seen[key] = len(props.Entries)
+
+		props.Entries = append(props.Entries, PropEntry{
+			Key:       key,
+			Value:     value,
+			Line:      start,
+			Column:    column,
+			Continued: continued,
+			Escaped:   keyEscaped || valueEscaped,
+		})
+	}
+
+	return props
+}
+
+// ParseModuleProp 按Root管理器的方式逐行解析 module.prop、system.prop
+// 只以第一个 = 分隔键和值并去掉两端空白，# 开头的行和没有 = 的行被忽略，
+// 不处理续行和转义，保证安装、校验时读到的值与 Magisk/KernelSU/APatch 一致；
+// 完整的 Java properties 解析 (ParseProperties) 只用于 lint 提示差异
+func ParseModuleProp(content string) *Properties {
+	props := &Properties{}
+	content = strings.TrimPrefix(content, "\uFEFF")
+
+	for i, raw := range strings.Split(content, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+
+		props.Entries = append(props.Entries, PropEntry{
+			Key:    key,
+			Value:  strings.TrimSpace(value),
+			Line:   i + 1,
+			Column: strings.Index(raw, key) + 1,
+		})
+	}
+
+	return props
+}
+
+// Map 返回键值表，重复的键取最后一次的值
+func (p *Properties) Map() map[string]string {
+	m := make(map[string]string, len(p.Entries))
+	for _, entry := range p.Entries {
+		m[entry.Key] = entry.Value
+	}
+	return m
+}
+
+// Lookup 查找键最后一次出现的位置
+func (p *Properties) Lookup(key string) (PropEntry, bool) {
+	for i := len(p.Entries) - 1; i >= 0; i-- {
+		if p.Entries[i].Key == key {
+			return p.Entries[i], true
+		}
+	}
+	return PropEntry{}, false
+}
+
+// warn 记录解析警告
+func (p *Properties) warn(line int, message string) {
+	p.Warnings = append(p.Warnings, PropWarning{Line: line, Message: message})
+}
+
+// endsWithContinuation 判断行是否以未转义的反斜杠结尾
+func endsWithContinuation(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitPropLine 在第一个未转义的 = : 或空白处分隔键和值
+func splitPropLine(line string) (key, value string, hasSeparator bool) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", line[i]) >= 0 {
+			end = i
+			break
+		}
+	}
+	if end == len(line) {
+		return line, "", false
+	}
+
+	rest := strings.TrimLeft(line[end:], " \t\f")
+	hasSeparator = rest != line[end:]
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+		hasSeparator = true
+	}
+	return line[:end], rest, hasSeparator
+}
+
+// unescape 处理转义序列，返回结果以及是否包含转义
+func (p *Properties) unescape(line int, s string) (string, bool) {
+	if !strings.Contains(s, "\\") {
+		return s, false
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 <= len(s) {
+				if code, err := strconv.ParseUint(s[i+1:i+5], 16, 16); err == nil {
+					b.WriteRune(rune(code))
+					i += 4
+					continue
+				}
+			}
+			p.warn(line, fmt.Sprintf("无效的 \\u 转义: %s", s[i-1:min(len(s), i+5)]))
+			b.WriteString("\\u")
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), true
+}
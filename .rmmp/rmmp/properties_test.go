@@ -0,0 +1,192 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseProperties(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		want     map[string]string
+		warnings []string
+	}{
+		{
+			name:    "basic",
+			content: "id=example\nname=Example Module\nversionCode=120\n",
+			want:    map[string]string{"id": "example", "name": "Example Module", "versionCode": "120"},
+		},
+		{
+			name:    "bom and crlf",
+			content: "\uFEFFid=example\r\nversion=v1.0\r\n",
+			want:    map[string]string{"id": "example", "version": "v1.0"},
+		},
+		{
+			name:    "comments and blank lines",
+			content: "# comment\n! another\n\n  id = example  \n",
+			want:    map[string]string{"id": "example"},
+		},
+		{
+			name:    "colon and whitespace separators",
+			content: "id:example\nauthor  someone\n",
+			want:    map[string]string{"id": "example", "author": "someone"},
+		},
+		{
+			name:    "continuation",
+			content: "description=first \\\n    second\n",
+			want:    map[string]string{"description": "first second"},
+		},
+		{
+			name:    "unicode escape",
+			content: "name=\\u4e2d\\u6587\n",
+			want:    map[string]string{"name": "中文"},
+		},
+		{
+			name:     "duplicate key keeps last",
+			content:  "id=first\nid=second\n",
+			want:     map[string]string{"id": "second"},
+			warnings: []string{"重复的键 id"},
+		},
+		{
+			name:     "empty key",
+			content:  "=value\nid=example\n",
+			want:     map[string]string{"id": "example"},
+			warnings: []string{"键为空"},
+		},
+		{
+			name:     "trailing continuation",
+			content:  "id=example\\",
+			want:     map[string]string{"id": "example"},
+			warnings: []string{"续行符"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			props := ParseProperties(tt.content)
+
+			got := props.Map()
+			if len(got) != len(tt.want) {
+				t.Errorf("Map() = %v, want %v", got, tt.want)
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Errorf("%s = %q, want %q", key, got[key], value)
+				}
+			}
+
+			if len(props.Warnings) != len(tt.warnings) {
+				t.Fatalf("warnings = %v, want %d", props.Warnings, len(tt.warnings))
+			}
+			for i, want := range tt.warnings {
+				if !strings.Contains(props.Warnings[i].Message, want) {
+					t.Errorf("warning %d = %q, want %q", i, props.Warnings[i].Message, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePropertiesPositions(t *testing.T) {
+	props := ParseProperties("# header\n  id=example\ndescription=a \\\n  b\n")
+
+	if len(props.Entries) != 2 {
+		t.Fatalf("entries = %v, want 2", props.Entries)
+	}
+	if e := props.Entries[0]; e.Line != 2 || e.Column != 3 || e.Continued {
+		t.Errorf("id entry = %+v, want line 2 column 3", e)
+	}
+	if e := props.Entries[1]; e.Line != 3 || !e.Continued {
+		t.Errorf("description entry = %+v, want line 3 continued", e)
+	}
+}
+
+func TestParseModuleProp(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+	}{
+		{
+			name:    "basic",
+			content: "id=example\nversionCode=120\n",
+			want:    map[string]string{"id": "example", "versionCode": "120"},
+		},
+		{
+			name:    "bom, crlf and spaces",
+			content: "\uFEFF id = example \r\nversion=v1.0\r\n",
+			want:    map[string]string{"id": "example", "version": "v1.0"},
+		},
+		{
+			name:    "only first equals splits",
+			content: "description=a=b\n",
+			want:    map[string]string{"description": "a=b"},
+		},
+		{
+			name:    "colon and whitespace are not separators",
+			content: "id:example\nauthor someone\nname=Example\n",
+			want:    map[string]string{"name": "Example"},
+		},
+		{
+			name:    "no continuation",
+			content: "id=exa\\\nmple\nversionCode=1\\\n2\n",
+			want:    map[string]string{"id": "exa\\", "versionCode": "1\\"},
+		},
+		{
+			name:    "escapes kept literally",
+			content: "name=\\u4e2d\nid=a\\tb\n",
+			want:    map[string]string{"name": "\\u4e2d", "id": "a\\tb"},
+		},
+		{
+			name:    "comments and empty keys",
+			content: "# id=commented\n! id=bang\n=value\n",
+			want:    map[string]string{"! id": "bang"},
+		},
+		{
+			name:    "duplicate key keeps last",
+			content: "id=first\nid=second\n",
+			want:    map[string]string{"id": "second"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseModuleProp(tt.content).Map()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateModuleZipReadsPropsLikeManagers(t *testing.T) {
+	// Java properties 会拼接续行得到 id=example，Root管理器读到的是 exa\
+	zipPath := writeTestZip(t, map[string]string{
+		"module.prop":  "id=exa\\\nmple\nname=Example\nversion=v1\nversionCode=1\n",
+		"customize.sh": "",
+	})
+
+	if _, err := (&RMMD{}).ValidateModuleZip(zipPath); err == nil {
+		t.Error("expected module id read line by line to be rejected")
+	}
+}
+
+func TestLintReportsManagerPropMismatch(t *testing.T) {
+	zipPath := writeTestZip(t, map[string]string{
+		"module.prop":  "id=example\nname=Example\nversion=v1\nversionCode=1\\\n2\nauthor=a\ndescription=d\n",
+		"customize.sh": "",
+	})
+
+	report, err := LintModule(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, issue := range report.Issues {
+		if issue.Level == "error" && strings.Contains(issue.Message, "versionCode 在Root管理器中读取为") {
+			return
+		}
+	}
+	t.Errorf("issues = %+v, want versionCode mismatch error", report.Issues)
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// 每个模块保留的还原点数量
+	maxRestorePoints = 5
+	// 还原点索引文件名
+	restoreIndexName = "index.json"
+)
+
+// RestorePoint 表示模块在某次安装、升级或卸载之前的状态
+type RestorePoint struct {
+	ID          string    `json:"id"`
+	Version     string    `json:"version"`
+	VersionCode int       `json:"versionCode"`
+	Reason      string    `json:"reason"` // upgrade, downgrade, reinstall, uninstall
+	CreatedAt   time.Time `json:"createdAt"`
+	// File 还原点目录中可安装的zip文件名
+	File string `json:"file"`
+	// Snapshot 为 true 表示zip由已安装的模块目录重新打包，否则为安装时使用的原始zip
+	Snapshot bool `json:"snapshot"`
+}
+
+// getRestoreDir 获取模块的还原点目录
+func getRestoreDir(moduleID string) string {
+	return filepath.Join(getDataDir(), "restore", moduleID)
+}
+
+// archiveName 安装时保存的原始zip文件名
+// 按sha256命名，同一版本代码重新打包的zip不会互相覆盖
+func archiveName(sum string) string {
+	if len(sum) > 16 {
+		sum = sum[:16]
+	}
+	return fmt.Sprintf("sha256-%s.zip", sum)
+}
+
+// loadRestorePoints 读取模块的还原点，按时间从旧到新排列
+func loadRestorePoints(moduleID string) ([]RestorePoint, error) {
+	data, err := os.ReadFile(filepath.Join(getRestoreDir(moduleID), restoreIndexName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取还原点失败: %v", err)
+	}
+
+	var points []RestorePoint
+	if err := json.Unmarshal(data, &points); err != nil {
+		return nil, fmt.Errorf("解析还原点失败: %v", err)
+	}
+	return points, nil
+}
+
+// saveRestorePoints 保存模块的还原点索引
+func saveRestorePoints(moduleID string, points []RestorePoint) error {
+	data, err := json.MarshalIndent(points, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化还原点失败: %v", err)
+	}
+	return os.WriteFile(filepath.Join(getRestoreDir(moduleID), restoreIndexName), data, 0644)
+}
+
+// createRestorePoint 在改变已安装的模块之前记录其当前版本
+// 如果安装记录中的原始zip仍然保存着则直接引用，否则将模块目录重新打包为快照
+func (r *RMMD) createRestorePoint(module ModuleInfo, reason string) error {
+	dir := getRestoreDir(module.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建还原点目录失败: %v", err)
+	}
+
+	points, err := loadRestorePoints(module.ID)
+	if err != nil {
+		return err
+	}
+
+	point := RestorePoint{
+		ID:          module.ID,
+		Version:     module.Version,
+		VersionCode: module.VersionCode,
+		Reason:      reason,
+		CreatedAt:   time.Now(),
+	}
+
+	if sum := installedSHA256(module); sum != "" {
+		point.File = archiveName(sum)
+	}
+
+	if point.File != "" && fileExists(filepath.Join(dir, point.File)) {
+		// 最新的还原点已经是同一个安装包时不再重复记录
+		if len(points) > 0 && points[len(points)-1].File == point.File {
+			return nil
+		}
+	} else {
+		point.Snapshot = true
+		point.File = fmt.Sprintf("snapshot-%d-%s.zip", module.VersionCode, point.CreatedAt.Format("20060102-150405"))
+		if err := r.ExportModule(module.ID, filepath.Join(dir, point.File)); err != nil {
+			os.Remove(filepath.Join(dir, point.File))
+			return fmt.Errorf("创建模块快照失败: %v", err)
+		}
+	}
+
+	points = append(points, point)
+	if len(points) > maxRestorePoints {
+		points = points[len(points)-maxRestorePoints:]
+	}
+
+	if err := saveRestorePoints(module.ID, points); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(msgOut, "💾 已创建还原点: %s %s (%s)\n", module.ID, module.Version, reason)
+	return pruneRestoreFiles(module.ID, points, "")
+}
+
+// installedSHA256 返回已安装模块的原始zip的sha256，未知时返回空
+// 安装记录的版本代码与当前模块一致时，记录中的sha256就是当前安装的zip
+func installedSHA256(module ModuleInfo) string {
+	records, err := loadInstallRecords()
+	if err != nil {
+		return ""
+	}
+	if record, ok := records[module.ID]; ok && record.VersionCode == module.VersionCode {
+		return record.Sha256
+	}
+	return ""
+}
+
+// archiveInstalledZip 保存本次安装使用的原始zip，供以后创建还原点
+// 已保存的同一安装包不会被覆盖，旧还原点引用的zip内容保持不变
+func archiveInstalledZip(zipInfo *ModuleZipInfo, sum string) error {
+	dir := getRestoreDir(zipInfo.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建还原点目录失败: %v", err)
+	}
+
+	name := archiveName(sum)
+	target := filepath.Join(dir, name)
+	if !fileExists(target) {
+		if err := copyFile(zipInfo.Path, target+".tmp"); err != nil {
+			os.Remove(target + ".tmp")
+			return fmt.Errorf("保存安装包失败: %v", err)
+		}
+		if err := os.Rename(target+".tmp", target); err != nil {
+			os.Remove(target + ".tmp")
+			return fmt.Errorf("保存安装包失败: %v", err)
+		}
+	}
+
+	points, err := loadRestorePoints(zipInfo.ID)
+	if err != nil {
+		return err
+	}
+	return pruneRestoreFiles(zipInfo.ID, points, name)
+}
+
+// pruneRestoreFiles 删除不再被还原点引用的zip
+// current 为当前安装的原始zip，为空时沿用安装记录
+func pruneRestoreFiles(moduleID string, points []RestorePoint, current string) error {
+	if current == "" {
+		records, err := loadInstallRecords()
+		if err != nil {
+			return err
+		}
+		if record, ok := records[moduleID]; ok && record.Sha256 != "" {
+			current = archiveName(record.Sha256)
+		}
+	}
+
+	keep := map[string]bool{
+		restoreIndexName: true,
+		current:          true,
+	}
+	for _, point := range points {
+		keep[point.File] = true
+	}
+
+	dir := getRestoreDir(moduleID)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !keep[entry.Name()] {
+			os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+	return nil
+}
+
+// copyFile 复制文件
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// findRestorePoint 查找要回滚到的还原点
+// to 为空时返回最新的还原点，否则按版本号或版本代码匹配最新的一个
+func findRestorePoint(moduleID, to string) (*RestorePoint, error) {
+	points, err := loadRestorePoints(moduleID)
+	if err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("模块 %s 没有还原点", moduleID)
+	}
+
+	for i := len(points) - 1; i >= 0; i-- {
+		point := points[i]
+		if to == "" || point.Version == to || strconv.Itoa(point.VersionCode) == to {
+			return &point, nil
+		}
+	}
+	return nil, fmt.Errorf("没有找到版本 %s 的还原点", to)
+}
+
+// RollbackModule 将模块重新安装为还原点中记录的版本
+func (r *RMMD) RollbackModule(moduleID, to string) (*RestorePoint, error) {
+	if !moduleIDPattern.MatchString(moduleID) {
+		return nil, fmt.Errorf("无效的模块ID: %s", moduleID)
+	}
+
+	point, err := findRestorePoint(moduleID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	zipPath := filepath.Join(getRestoreDir(moduleID), point.File)
+	fmt.Fprintf(msgOut, "⏪ 正在回滚 %s 到 %s (%s)...\n", moduleID, point.Version, point.CreatedAt.Format("2006-01-02 15:04:05"))
+
+	// 回滚是恢复到之前的状态，不因文件冲突中断
+	if err := r.InstallModule(zipPath, InstallOptions{ConflictPolicy: ConflictContinue}); err != nil {
+		return nil, err
+	}
+	return point, nil
+}
+
+// printRestorePoints 打印模块的还原点
+func printRestorePoints(moduleID string) error {
+	points, err := loadRestorePoints(moduleID)
+	if err != nil {
+		return err
+	}
+
+	if machineOutput() {
+		if points == nil {
+			points = []RestorePoint{}
+		}
+		return printData(points)
+	}
+
+	if len(points) == 0 {
+		fmt.Fprintf(msgOut, "📋 模块 %s 没有还原点\n", moduleID)
+		return nil
+	}
+
+	fmt.Fprintf(msgOut, "📋 模块 %s 的还原点 (共 %d 个，最新的在前):\n", moduleID, len(points))
+	for i := len(points) - 1; i >= 0; i-- {
+		point := points[i]
+		kind := "原始安装包"
+		if point.Snapshot {
+			kind = "目录快照"
+		}
+		fmt.Fprintf(msgOut, "   %s  %-20s %-10s %s\n", point.CreatedAt.Format("2006-01-02 15:04:05"),
+			fmt.Sprintf("%s (%d)", point.Version, point.VersionCode), point.Reason, kind)
+	}
+	return nil
+}
+
+// handleRollbackCommand 处理 module rollback 命令
+func handleRollbackCommand(args []string) {
+	var moduleID, to string
+	list := false
+
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		switch name {
+		case "--to":
+			if !hasValue {
+				if i+1 >= len(args) {
+					fmt.Fprintln(msgOut, "错误: --to 需要一个参数")
+					return
+				}
+				i++
+				value = args[i]
+			}
+			to = value
+		case "--list", "-l":
+			list = true
+		default:
+			moduleID = args[i]
+		}
+	}
+
+	if moduleID == "" {
+		fmt.Fprintln(msgOut, "错误: 请指定模块ID")
+		fmt.Fprintln(msgOut, "用法: rmmp module rollback <模块ID> [--to <版本>] [--list]")
+		return
+	}
+
+	if list {
+		if err := printRestorePoints(moduleID); err != nil {
+			fmt.Fprintf(msgOut, "❌ %v\n", err)
+		}
+		return
+	}
+
+	rmmd := NewRMMD()
+	point, err := rmmd.RollbackModule(moduleID, to)
+	if err != nil {
+		fmt.Fprintf(msgOut, "❌ 回滚失败: %v\n", err)
+		return
+	}
+
+	if machineOutput() {
+		if err := printData(point); err != nil {
+			fmt.Fprintf(msgOut, "❌ %v\n", err)
+		}
+		return
+	}
+	fmt.Fprintf(msgOut, "✅ 已回滚 %s 到 %s\n", moduleID, point.Version)
+	fmt.Fprintln(msgOut, "🔄 需要重启设备后生效")
+}
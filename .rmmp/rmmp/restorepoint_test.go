@@ -0,0 +1,92 @@
+package main
+
+import (
+	"archive/zip"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+// readZipFile 读取zip中单个文件的内容
+func readZipFile(t *testing.T, zipPath, name string) string {
+	t.Helper()
+
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	for _, f := range reader.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	t.Fatalf("%s 中没有 %s", zipPath, name)
+	return ""
+}
+
+func TestRestorePointsForReinstalls(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	builds := map[string]string{}
+	for _, marker := range []string{"v1a", "v1b", "v1c"} {
+		builds[marker] = testModuleZip(t, "demo", 1, map[string]string{"marker": marker})
+	}
+
+	r := NewRMMDWithBackend(NewFakeBackend())
+	steps := []struct {
+		install string
+		// 每个还原点中zip的 marker，从旧到新
+		want []string
+	}{
+		{install: "v1a", want: nil},
+		{install: "v1b", want: []string{"v1a"}},
+		// 重新安装相同的zip不产生还原点
+		{install: "v1b", want: []string{"v1a"}},
+		{install: "v1c", want: []string{"v1a", "v1b"}},
+		{install: "v1a", want: []string{"v1a", "v1b", "v1c"}},
+	}
+
+	for i, step := range steps {
+		if err := r.InstallModule(builds[step.install], InstallOptions{ConflictPolicy: ConflictAbort}); err != nil {
+			t.Fatalf("step %d: install %s: %v", i, step.install, err)
+		}
+
+		points, err := loadRestorePoints("demo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, point := range points {
+			got = append(got, readZipFile(t, filepath.Join(getRestoreDir("demo"), point.File), "marker"))
+		}
+		if len(got) != len(step.want) {
+			t.Fatalf("step %d: restore points = %q, want %q", i, got, step.want)
+		}
+		for j := range got {
+			if got[j] != step.want[j] {
+				t.Errorf("step %d: restore point %d = %q, want %q", i, j, got[j], step.want[j])
+			}
+		}
+	}
+
+	// 回滚到最新的还原点安装的是被覆盖之前的 v1c
+	point, err := r.RollbackModule("demo", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readZipFile(t, filepath.Join(getRestoreDir("demo"), point.File), "marker"); got != "v1c" {
+		t.Errorf("rollback installed %q, want v1c", got)
+	}
+}
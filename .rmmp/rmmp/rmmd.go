@@ -1,46 +1,29 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 )
 
-// ModuleInfo 表示模块信息的结构
-type ModuleInfo struct {
-	ID          string `json:"id"`
-	UpdateJSON  string `json:"updateJson"`
-	VersionCode string `json:"versionCode"`
-	Description string `json:"description"`
-	Enabled     string `json:"enabled"`
-	Update      string `json:"update"`
-	Name        string `json:"name"`
-	Web         string `json:"web"`
-	Version     string `json:"version"`
-	Author      string `json:"author"`
-	DirID       string `json:"dir_id"`
-	Action      string `json:"action"`
-	Remove      string `json:"remove"`
-}
-
-// RootEnvironment 表示Root环境类型
-type RootEnvironment int
-
 const (
-	RootUnknown RootEnvironment = iota
-	RootMagisk
-	RootAPatch
-	RootKernelSU
+	// 模块安装目录
+	modulesDir = "/data/adb/modules"
 )
 
+// moduleIDPattern 模块ID的合法格式（与Magisk的要求一致）
+var moduleIDPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9._-]+$`)
+
 // RMMD Root模块管理器守护进程
 type RMMD struct {
-	rootEnv    RootEnvironment
-	binaryPath string
+	backend RootBackend
+	// probes 创建时各Root环境的检测结果，指定后端创建时为空
+	probes []RootProbe
+	// rootVersion 检测时得到的管理器版本，未执行管理器时为零值
+	rootVersion RootVersion
 }
 
 // NewRMMD 创建新的RMMD实例
@@ -50,35 +33,61 @@ func NewRMMD() *RMMD {
 	return rmmd
 }
 
+// NewRMMDWithBackend 使用指定的Root后端创建RMMD实例（不进行环境检测）
+func NewRMMDWithBackend(backend RootBackend) *RMMD {
+	return &RMMD{backend: backend}
+}
+
 // detectRootEnvironment 检测Root环境类型
+// 每个环境都会实际执行管理器二进制，避免残留目录导致选错管理器
 func (r *RMMD) detectRootEnvironment() {
-	// 检查文件夹是否存在来判断Root环境类型
-	if r.dirExists("/data/adb/magisk") {
-		r.rootEnv = RootMagisk
-		r.binaryPath = "/data/adb/magisk/magisk"
-		fmt.Println("🔍 检测到 Magisk 环境")
-	} else if r.dirExists("/data/adb/ap") {
-		r.rootEnv = RootAPatch
-		r.binaryPath = "/data/adb/apd"
-		fmt.Println("🔍 检测到 APatch 环境")
-	} else if r.dirExists("/data/adb/ksu") {
-		r.rootEnv = RootKernelSU
-		r.binaryPath = "/data/adb/ksud"
-		fmt.Println("🔍 检测到 KernelSU 环境")
-	} else {
-		r.rootEnv = RootUnknown
-		fmt.Println("⚠️  未检测到支持的Root环境")
+	r.backend = nil
+	probes := r.probeRootBackends()
+	r.probes = probes
+	index, reason, err := chooseRootBackend(probes)
+	if err != nil {
+		fmt.Fprintf(msgOut, "⚠️  %v\n", err)
+		return
+	}
+
+	chosen := probes[index]
+	r.rootVersion = chosen.RootVersion
+	r.backend = chosen.factory.New(r)
+	fmt.Fprintf(msgOut, "🔍 检测到 %s 环境\n", chosen.DisplayName())
+
+	if rootEnvOverride != "" {
+		if !chosen.MarkerFound {
+			fmt.Fprintf(msgOut, "⚠️  指定的 %s 环境不存在 %s\n", chosen.Backend, chosen.Marker)
+		} else if chosen.Problem != "" {
+			fmt.Fprintf(msgOut, "⚠️  %s: %s\n", chosen.Backend, chosen.Problem)
+		}
+		return
+	}
+
+	if !chosen.Runnable || (sysroot == "" && !chosen.Active) {
+		fmt.Fprintf(msgOut, "⚠️  %s\n", reason)
+	}
+
+	var others []string
+	for i, probe := range probes {
+		if i != index && probe.conflicting() {
+			others = append(others, probe.DisplayName())
+		}
+	}
+	if len(others) > 0 {
+		fmt.Fprintf(msgOut, "⚠️  同时存在 %s，已选择 %s (%s)，可用 --root-env 指定\n",
+			strings.Join(others, ", "), chosen.Backend, reason)
 	}
 }
 
 // dirExists 检查目录是否存在
 func (r *RMMD) dirExists(path string) bool {
-	if runtime.GOOS != "android" && strings.HasPrefix(path, "/data/adb/") {
-		// 非Android环境下跳过检测
+	if sysroot == "" && runtime.GOOS != "android" && strings.HasPrefix(path, "/data/adb/") {
+		// 非Android环境下且未指定sysroot时跳过检测
 		return false
 	}
 
-	info, err := os.Stat(path)
+	info, err := os.Stat(hostPath(path))
 	if err != nil {
 		return false
 	}
@@ -87,40 +96,46 @@ func (r *RMMD) dirExists(path string) bool {
 
 // fileExists 检查文件是否存在
 func (r *RMMD) fileExists(path string) bool {
-	if runtime.GOOS != "android" && strings.HasPrefix(path, "/data/adb/") {
-		// 非Android环境下跳过检测
+	if sysroot == "" && runtime.GOOS != "android" && strings.HasPrefix(path, "/data/adb/") {
+		// 非Android环境下且未指定sysroot时跳过检测
 		return false
 	}
 
-	_, err := os.Stat(path)
+	_, err := os.Stat(hostPath(path))
 	return !os.IsNotExist(err)
 }
 
 // ListModules 列出已安装的模块
 func (r *RMMD) ListModules() ([]ModuleInfo, error) {
-	switch r.rootEnv {
-	case RootMagisk:
-		return r.listMagiskModules()
-	case RootAPatch:
-		return r.listModulesViaCommand("module", "list")
-	case RootKernelSU:
-		return r.listModulesViaCommand("module", "list")
-	default:
+	if r.backend == nil {
 		return nil, fmt.Errorf("未检测到支持的Root环境")
 	}
+
+	modules, err := r.backend.ListModules()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range modules {
+		normalizeModule(&modules[i])
+		// 管理器没有报告时，modules_update 中有暂存的模块同样表示待重启的更新
+		if !modules[i].Update {
+			modules[i].Update = r.dirExists(filepath.Join(modulesUpdateDir, modules[i].DirID))
+		}
+	}
+	return modules, nil
 }
 
 // listMagiskModules 列出Magisk模块（自己实现）
 func (r *RMMD) listMagiskModules() ([]ModuleInfo, error) {
 	modules := []ModuleInfo{}
-	modulesDir := "/data/adb/modules"
 
 	if !r.dirExists(modulesDir) {
 		return modules, fmt.Errorf("模块目录不存在: %s", modulesDir)
 	}
 
 	// 遍历模块目录
-	entries, err := os.ReadDir(modulesDir)
+	entries, err := os.ReadDir(hostPath(modulesDir))
 	if err != nil {
 		return nil, fmt.Errorf("读取模块目录失败: %v", err)
 	}
@@ -140,7 +155,7 @@ func (r *RMMD) listMagiskModules() ([]ModuleInfo, error) {
 
 		moduleInfo, err := r.parseMagiskModule(moduleID, modulePath)
 		if err != nil {
-			fmt.Printf("⚠️  解析模块 %s 失败: %v\n", moduleID, err)
+			fmt.Fprintf(msgOut, "⚠️  解析模块 %s 失败: %v\n", moduleID, err)
 			continue
 		}
 
@@ -160,177 +175,344 @@ func (r *RMMD) parseMagiskModule(moduleID, modulePath string) (*ModuleInfo, erro
 	}
 
 	// 读取module.prop文件
-	content, err := os.ReadFile(propFile)
+	content, err := os.ReadFile(hostPath(propFile))
 	if err != nil {
 		return nil, fmt.Errorf("读取module.prop失败: %v", err)
 	}
 
 	// 解析module.prop
-	props := r.parseProperties(string(content))
-
-	// 检查模块是否启用
-	enabled := "true"
-	disableFile := filepath.Join(modulePath, "disable")
-	if r.fileExists(disableFile) {
-		enabled = "false"
-	}
-
-	// 检查是否有更新
-	updateJSON := props["updateJson"]
-	hasUpdate := "false"
-	if updateJSON != "" {
-		hasUpdate = "true" // 简化处理，实际应该检查版本
-	}
-
-	module := &ModuleInfo{
-		ID:          moduleID,
-		UpdateJSON:  updateJSON,
-		VersionCode: props["versionCode"],
-		Description: props["description"],
-		Enabled:     enabled,
-		Update:      hasUpdate,
-		Name:        props["name"],
-		Web:         "false", // Magisk模块通常没有web界面
-		Version:     props["version"],
-		Author:      props["author"],
-		DirID:       moduleID,
-		Action:      "true",  // 假设都有action
-		Remove:      "false", // 假设都可以删除
-	}
-
-	return module, nil
+	props := ParseModuleProp(string(content)).Map()
+
+	// 检测模块功能和状态标记
+	module := moduleFromProps(moduleID, props, r.detectCapabilities(modulePath))
+	return &module, nil
 }
 
-// parseProperties 解析properties格式的文件内容
-func (r *RMMD) parseProperties(content string) map[string]string {
-	props := make(map[string]string)
-	lines := strings.Split(content, "\n")
+// InstallOptions 安装模块的选项
+type InstallOptions struct {
+	// ConflictPolicy 文件冲突处理策略: ask(默认)、abort、continue、disable
+	ConflictPolicy string
+	// Source 模块来源仓库 (username/repo)，为空时沿用之前的安装记录
+	Source string
+	// ZipURL 模块的下载链接，本地安装时为空
+	ZipURL string
+	// Proxy 下载模块时使用的GitHub代理，只用于记录操作历史
+	Proxy string
+	// Origin 用户指定的安装来源（路径、链接或 -），只用于记录操作历史
+	Origin string
+	// Dependencies update.json 中声明的依赖，只用于 InstallModuleWithDeps
+	Dependencies []string
+	// SkipDeps 跳过依赖解析，只用于 InstallModuleWithDeps
+	SkipDeps bool
+}
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+// InstallModule 安装模块，无论成功与否都会写入操作历史
+func (r *RMMD) InstallModule(zipPath string, opts InstallOptions) (err error) {
+	entry := HistoryEntry{Action: "install", Source: opts.Source, Proxy: opts.Proxy}
+	if entry.Source == "" {
+		entry.Source = opts.ZipURL
+	}
+	if entry.Source == "" {
+		entry.Source = opts.Origin
+	}
+	if entry.Source == "" {
+		entry.Source = zipPath
+	}
+	defer func() {
+		entry.Outcome = OutcomeSuccess
+		if err != nil {
+			entry.Outcome = OutcomeFailed
+			entry.Error = err.Error()
+		}
+		recordHistory(entry)
+	}()
+
+	if r.backend == nil {
+		return fmt.Errorf("未检测到支持的Root环境")
+	}
+
+	// 获取绝对路径
+	absPath, err := filepath.Abs(zipPath)
+	if err != nil {
+		return fmt.Errorf("获取绝对路径失败: %v", err)
+	}
+
+	fmt.Fprintln(msgOut, "🔎 正在校验模块...")
+	zipInfo, err := r.ValidateModuleZip(absPath)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(msgOut, "✅ 模块校验通过: %s (%s)\n", zipInfo.ID, zipInfo.Props["version"])
+
+	entry.ID = zipInfo.ID
+	entry.ToVersion = zipInfo.Props["version"]
+	entry.ToVersionCode, _ = parseIntString(zipInfo.Props["versionCode"])
+	if entry.Sha256, err = fileSHA256(absPath); err != nil {
+		return fmt.Errorf("计算sha256失败: %v", err)
+	}
+
+	existing, findErr := r.findModule(zipInfo.ID)
+	if findErr == nil {
+		entry.FromVersion = existing.Version
+		entry.FromVersionCode = existing.VersionCode
+		switch {
+		case entry.ToVersionCode > existing.VersionCode:
+			entry.Action = "upgrade"
+		case entry.ToVersionCode < existing.VersionCode:
+			entry.Action = "downgrade"
+		default:
+			entry.Action = "reinstall"
 		}
+	}
+
+	fmt.Fprintln(msgOut, "🔎 正在检测文件冲突...")
+	if err := r.resolveConflicts(zipInfo, opts.ConflictPolicy); err != nil {
+		return err
+	}
 
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) == 2 {
-			key := strings.TrimSpace(parts[0])
-			value := strings.TrimSpace(parts[1])
-			props[key] = value
+	// 覆盖已安装的模块之前先记录还原点，失败不影响安装
+	// 重新安装完全相同的zip时无需还原点
+	if findErr == nil && installedSHA256(*existing) != entry.Sha256 {
+		if err := r.createRestorePoint(*existing, entry.Action); err != nil {
+			fmt.Fprintf(msgOut, "⚠️  创建还原点失败: %v\n", err)
 		}
 	}
 
-	return props
+	// sysroot 中的Root管理器无法在本机执行，改用内置安装器
+	installer := r.backend
+	if _, native := installer.(*NativeBackend); sysroot != "" && !native {
+		installer = &NativeBackend{rmmd: r}
+	}
+	fmt.Fprintf(msgOut, "🚀 使用 %s 安装模块: %s\n", installer.Name(), absPath)
+
+	if err := installer.InstallModule(absPath); err != nil {
+		return fmt.Errorf("安装失败: %v", err)
+	}
+
+	if err := archiveInstalledZip(zipInfo, entry.Sha256); err != nil {
+		fmt.Fprintf(msgOut, "⚠️  保存安装包失败: %v\n", err)
+	}
+	if err := saveInstallRecord(zipInfo, opts, entry.Sha256); err != nil {
+		fmt.Fprintf(msgOut, "⚠️  保存安装记录失败: %v\n", err)
+	}
+
+	fmt.Fprintln(msgOut, "✅ 模块安装完成!")
+	return nil
+}
+
+// ModuleActionResult 表示对模块执行启用/禁用/卸载等操作的结果
+type ModuleActionResult struct {
+	ID             string `json:"id"`
+	Action         string `json:"action"`
+	Changed        bool   `json:"changed"`
+	RebootRequired bool   `json:"rebootRequired"`
 }
 
-// listModulesViaCommand 通过命令行列出模块
-func (r *RMMD) listModulesViaCommand(args ...string) ([]ModuleInfo, error) {
-	if !r.fileExists(r.binaryPath) {
-		return nil, fmt.Errorf("二进制文件不存在: %s", r.binaryPath)
+// findModule 在已安装的模块中查找指定ID的模块
+func (r *RMMD) findModule(moduleID string) (*ModuleInfo, error) {
+	if !moduleIDPattern.MatchString(moduleID) {
+		return nil, fmt.Errorf("无效的模块ID: %s", moduleID)
 	}
 
-	cmd := exec.Command(r.binaryPath, args...)
-	output, err := cmd.Output()
+	modules, err := r.ListModules()
 	if err != nil {
-		return nil, fmt.Errorf("执行命令失败: %v", err)
+		return nil, err
 	}
 
-	var modules []ModuleInfo
-	if err := json.Unmarshal(output, &modules); err != nil {
-		return nil, fmt.Errorf("解析JSON失败: %v", err)
+	for i := range modules {
+		if modules[i].ID == moduleID {
+			return &modules[i], nil
+		}
 	}
+	return nil, fmt.Errorf("模块未安装: %s", moduleID)
+}
 
-	return modules, nil
+// EnableModule 启用模块
+func (r *RMMD) EnableModule(moduleID string) (*ModuleActionResult, error) {
+	return r.changeModuleState(moduleID, "enable")
 }
 
-// InstallModule 安装模块
-func (r *RMMD) InstallModule(zipPath string) error {
-	if r.rootEnv == RootUnknown {
-		return fmt.Errorf("未检测到支持的Root环境")
+// DisableModule 禁用模块
+func (r *RMMD) DisableModule(moduleID string) (*ModuleActionResult, error) {
+	return r.changeModuleState(moduleID, "disable")
+}
+
+// UninstallModule 卸载模块（标记为删除，重启后生效）
+func (r *RMMD) UninstallModule(moduleID string) (*ModuleActionResult, error) {
+	return r.changeModuleState(moduleID, "uninstall")
+}
+
+// UndoUninstallModule 撤销尚未生效的卸载操作
+func (r *RMMD) UndoUninstallModule(moduleID string) (*ModuleActionResult, error) {
+	return r.changeModuleState(moduleID, "undo-uninstall")
+}
+
+// changeModuleState 通过Root后端改变模块状态，状态已符合要求时不做任何操作
+func (r *RMMD) changeModuleState(moduleID, action string) (*ModuleActionResult, error) {
+	module, err := r.findModule(moduleID)
+	if err != nil {
+		return nil, err
 	}
 
-	if !r.fileExists(r.binaryPath) {
-		return fmt.Errorf("二进制文件不存在: %s", r.binaryPath)
+	var done bool
+	var apply func(string) error
+	switch action {
+	case "enable":
+		done, apply = module.Enabled, r.backend.EnableModule
+	case "disable":
+		done, apply = !module.Enabled, r.backend.DisableModule
+	case "uninstall":
+		done, apply = module.Remove, r.backend.UninstallModule
+	case "undo-uninstall":
+		done, apply = !module.Remove, r.backend.UndoUninstallModule
+	default:
+		return nil, fmt.Errorf("不支持的操作: %s", action)
 	}
 
-	// 获取绝对路径
-	absPath, err := filepath.Abs(zipPath)
-	if err != nil {
-		return fmt.Errorf("获取绝对路径失败: %v", err)
+	result := &ModuleActionResult{
+		ID:     moduleID,
+		Action: action,
+	}
+	if done {
+		return result, nil
 	}
 
-	fmt.Printf("🚀 使用 %s 安装模块: %s\n", r.getRootEnvName(), absPath)
+	if action == "uninstall" {
+		if err := r.createRestorePoint(*module, action); err != nil {
+			fmt.Fprintf(msgOut, "⚠️  创建还原点失败: %v\n", err)
+		}
+	}
 
-	var cmd *exec.Cmd
-	switch r.rootEnv {
-	case RootMagisk:
-		cmd = exec.Command(r.binaryPath, "--install-module", absPath)
-	case RootAPatch, RootKernelSU:
-		cmd = exec.Command(r.binaryPath, "module", "install", absPath)
+	entry := HistoryEntry{
+		Action:          action,
+		ID:              moduleID,
+		FromVersion:     module.Version,
+		FromVersionCode: module.VersionCode,
+		Outcome:         OutcomeSuccess,
+	}
+	if err := apply(moduleID); err != nil {
+		entry.Outcome = OutcomeFailed
+		entry.Error = err.Error()
+		recordHistory(entry)
+		return nil, err
 	}
+	recordHistory(entry)
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	result.Changed = true
+	result.RebootRequired = true
+	return result, nil
+}
 
-	err = cmd.Run()
-	if err != nil {
-		return fmt.Errorf("安装失败: %v", err)
+// writeMarker 创建或删除标记文件
+func (r *RMMD) writeMarker(markerPath string, present bool) error {
+	if present {
+		if err := os.WriteFile(hostPath(markerPath), nil, 0644); err != nil {
+			return fmt.Errorf("创建标记文件失败: %v", err)
+		}
+		return nil
 	}
 
-	fmt.Println("✅ 模块安装完成!")
+	if err := os.Remove(hostPath(markerPath)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除标记文件失败: %v", err)
+	}
 	return nil
 }
 
 // getRootEnvName 获取Root环境名称
 func (r *RMMD) getRootEnvName() string {
-	switch r.rootEnv {
-	case RootMagisk:
-		return "Magisk"
-	case RootAPatch:
-		return "APatch"
-	case RootKernelSU:
-		return "KernelSU"
-	default:
+	if r.backend == nil {
 		return "Unknown"
 	}
+	return r.backend.Name()
 }
 
-// PrintModuleList 打印模块列表（格式化输出）
-func (r *RMMD) PrintModuleList() error {
+// PrintModuleList 按条件过滤、排序后打印模块列表（格式化输出）
+func (r *RMMD) PrintModuleList(opts ListOptions) error {
 	modules, err := r.ListModules()
 	if err != nil {
 		return err
 	}
 
 	if len(modules) == 0 {
-		fmt.Println("📋 没有找到已安装的模块")
+		if machineOutput() {
+			return printData(modules)
+		}
+		fmt.Fprintln(msgOut, "📋 没有找到已安装的模块")
+		return nil
+	}
+
+	// 检查更新（结果会被缓存）
+	results := NewUpdateChecker().CheckModules(modules)
+	return printModuleList(r.getRootEnvName(), modules, results, opts)
+}
+
+// printModuleList 根据更新检查结果过滤、排序并打印模块列表
+func printModuleList(rootEnv string, modules []ModuleInfo, results []UpdateCheckResult, opts ListOptions) error {
+	ApplyUpdateStatus(modules, results)
+
+	total := len(modules)
+	modules = filterModules(modules, opts)
+	var sizes map[string]int64
+	if opts.Sort == "size" {
+		sizes = moduleSizes(modules)
+	}
+	sortModules(modules, opts.Sort, sizes)
+
+	if machineOutput() {
+		return printData(modules)
+	}
+
+	if len(modules) == 0 {
+		fmt.Fprintf(msgOut, "📋 共 %d 个模块，没有符合条件的模块\n", total)
 		return nil
 	}
 
-	fmt.Printf("📋 已安装的模块列表 (%s) - 共 %d 个:\n", r.getRootEnvName(), len(modules))
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	checked := make(map[string]bool)
+	for _, result := range results {
+		checked[result.ID] = result.Error == ""
+	}
+
+	if opts.filtered() {
+		fmt.Fprintf(msgOut, "📋 已安装的模块列表 (%s) - 共 %d 个，符合条件 %d 个:\n", rootEnv, total, len(modules))
+	} else {
+		fmt.Fprintf(msgOut, "📋 已安装的模块列表 (%s) - 共 %d 个:\n", rootEnv, len(modules))
+	}
+	fmt.Fprintln(msgOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+
+	if opts.Compact {
+		printCompactModuleList(modules, checked, sizes)
+		return nil
+	}
 
 	for i, module := range modules {
 		status := "🔴 已禁用"
-		if module.Enabled == "true" {
+		if module.Enabled {
 			status = "🟢 已启用"
 		}
 
-		fmt.Printf("%d. %s (%s)\n", i+1, module.Name, module.ID)
-		fmt.Printf("   版本: %s (代码: %s)\n", module.Version, module.VersionCode)
-		fmt.Printf("   作者: %s\n", module.Author)
-		fmt.Printf("   状态: %s\n", status)
+		fmt.Fprintf(msgOut, "%d. %s (%s)\n", i+1, module.Name, module.ID)
+		fmt.Fprintf(msgOut, "   版本: %s (代码: %d)\n", module.Version, module.VersionCode)
+		fmt.Fprintf(msgOut, "   作者: %s\n", module.Author)
+		fmt.Fprintf(msgOut, "   状态: %s\n", status)
+		if module.Update {
+			fmt.Fprintln(msgOut, "   ⏳ 更新已暂存，重启后生效")
+		}
 		if module.Description != "" {
-			fmt.Printf("   描述: %s\n", module.Description)
+			fmt.Fprintf(msgOut, "   描述: %s\n", module.Description)
+		}
+		if sizes != nil {
+			fmt.Fprintf(msgOut, "   大小: %s\n", formatSize(sizes[module.ID]))
 		}
 		if module.UpdateJSON != "" {
 			updateStatus := "🔄 有更新"
-			if module.Update == "false" {
+			if !checked[module.ID] {
+				updateStatus = "❔ 检查失败"
+			} else if !module.Outdated {
 				updateStatus = "✅ 最新版本"
 			}
-			fmt.Printf("   更新: %s\n", updateStatus)
+			fmt.Fprintf(msgOut, "   更新: %s\n", updateStatus)
 		}
-		fmt.Println("   ────────────────────────────────────────")
+		fmt.Fprintln(msgOut, "   ────────────────────────────────────────")
 	}
 
 	return nil
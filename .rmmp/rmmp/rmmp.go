@@ -12,44 +12,110 @@ const (
 )
 
 func main() {
-	if len(os.Args) < 2 {
+	if format := os.Getenv(outputEnv); format != "" {
+		if err := setOutputFormat(format); err != nil {
+			fmt.Fprintf(msgOut, "错误: %v\n", err)
+			return
+		}
+	}
+
+	args, err := parseGlobalFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(msgOut, "错误: %v\n", err)
+		return
+	}
+
+	if len(args) < 1 {
 		showHelp()
 		return
 	}
 
-	command := os.Args[1]
+	command := args[0]
 	switch command {
 	case "module":
-		if len(os.Args) < 3 {
-			fmt.Println("错误: 缺少子命令")
+		if len(args) < 2 {
+			fmt.Fprintln(msgOut, "错误: 缺少子命令")
 			showModuleHelp()
 			return
 		}
-		handleModuleCommand(os.Args[2:])
+		handleModuleCommand(args[1:])
 	case "get":
 		var repo string
-		if len(os.Args) < 3 {
+		var opts InstallOptions
+		for _, arg := range args[1:] {
+			if arg == "--skip-deps" {
+				opts.SkipDeps = true
+			} else {
+				repo = arg
+			}
+		}
+		if repo == "" {
 			// 默认为ROOTMMP/rmmp (自我更新)
 			repo = "ROOTMMP/rmmp"
-			fmt.Println("🔄 未指定仓库，默认进行自我更新...")
-		} else {
-			repo = os.Args[2]
+			fmt.Fprintln(msgOut, "🔄 未指定仓库，默认进行自我更新...")
 		}
-		handleGetCommand(repo)
+		handleGetCommand(repo, opts)
+	case "export":
+		handleLockExportCommand(args[1:])
+	case "sync":
+		handleSyncCommand(args[1:])
+	case "history":
+		handleHistoryCommand(args[1:])
+	case "status":
+		handleStatusCommand()
+	case "daemon":
+		handleDaemonCommand(args[1:])
+	case "doctor":
+		handleDoctorCommand()
 	case "proxy":
-		handleProxyCommand(os.Args[2:])
+		handleProxyCommand(args[1:])
 	case "search":
-		handleSearchCommand(os.Args[2:])
+		handleSearchCommand(args[1:])
 	case "version", "-v", "--version":
-		fmt.Printf("rmmp version %s\n", version)
+		fmt.Fprintf(msgOut, "rmmp version %s\n", version)
 	case "help", "-h", "--help":
 		showHelp()
 	default:
-		fmt.Printf("未知命令: %s\n", command)
+		fmt.Fprintf(msgOut, "未知命令: %s\n", command)
 		showHelp()
 	}
 }
 
+// parseGlobalFlags 解析出现在子命令之前的全局参数，返回剩余参数
+func parseGlobalFlags(args []string) ([]string, error) {
+	for len(args) > 0 {
+		name, value, hasValue := strings.Cut(args[0], "=")
+		switch name {
+		case "--sysroot", "--output", "-o", "--root-env":
+		default:
+			return args, nil
+		}
+
+		if !hasValue {
+			if len(args) < 2 {
+				return nil, fmt.Errorf("%s 需要一个参数", name)
+			}
+			value = args[1]
+			args = args[1:]
+		}
+
+		switch name {
+		case "--sysroot":
+			if err := setSysroot(value); err != nil {
+				return nil, fmt.Errorf("无效的 sysroot: %v", err)
+			}
+		case "--output", "-o":
+			if err := setOutputFormat(value); err != nil {
+				return nil, err
+			}
+		case "--root-env":
+			rootEnvOverride = value
+		}
+		args = args[1:]
+	}
+	return args, nil
+}
+
 // 处理模块相关命令
 func handleModuleCommand(args []string) {
 	if len(args) < 1 {
@@ -60,60 +126,206 @@ func handleModuleCommand(args []string) {
 	subCommand := args[0]
 	switch subCommand {
 	case "install":
+		handleInstallCommand(args[1:])
+	case "list":
+		opts, err := parseListArgs(args[1:])
+		if err != nil {
+			fmt.Fprintf(msgOut, "错误: %v\n", err)
+			fmt.Fprintln(msgOut, "用法: rmmp module list [关键词] [--enabled|--disabled] [--author <作者>] [--has-update] [--has-webui] [--sort name|id|version|size|installed-at] [--compact]")
+			return
+		}
+		listModules(opts)
+	case "info", "show":
 		if len(args) < 2 {
-			fmt.Println("错误: 请指定要安装的zip文件")
-			fmt.Println("用法: rmmp module install <module.zip>")
+			fmt.Fprintln(msgOut, "错误: 请指定模块ID")
+			fmt.Fprintln(msgOut, "用法: rmmp module info <模块ID>")
 			return
 		}
-		installModule(args[1])
-	case "list":
-		listModules()
+		rmmd := NewRMMD()
+		if err := rmmd.PrintModuleDetails(args[1]); err != nil {
+			fmt.Fprintf(msgOut, "❌ 获取模块信息失败: %v\n", err)
+		}
+	case "outdated":
+		refresh := len(args) > 1 && args[1] == "--refresh"
+		if client := dialDaemon(); client != nil {
+			defer client.Close()
+			var results []UpdateCheckResult
+			err := client.Call("checkUpdates", daemonUpdateParams{Refresh: refresh}, &results)
+			if err == nil {
+				err = printOutdatedResults(results)
+			}
+			if err != nil {
+				fmt.Fprintf(msgOut, "❌ 检查更新失败: %v\n", err)
+			}
+			return
+		}
+		rmmd := NewRMMD()
+		if err := rmmd.PrintOutdatedModules(refresh); err != nil {
+			fmt.Fprintf(msgOut, "❌ 检查更新失败: %v\n", err)
+		}
+	case "upgrade", "update":
+		handleUpgradeCommand(args[1:])
+	case "backup":
+		handleBackupCommand(args[1:])
+	case "restore":
+		handleRestoreCommand(args[1:])
+	case "export":
+		handleExportCommand(args[1:])
+	case "rollback":
+		handleRollbackCommand(args[1:])
+	case "lint":
+		handleLintCommand(args[1:])
+	case "hold":
+		handleHoldCommand(true, args[1:])
+	case "unhold":
+		handleHoldCommand(false, args[1:])
+	case "uninstall", "remove", "enable", "disable", "undo-uninstall":
+		if len(args) < 2 {
+			fmt.Fprintln(msgOut, "错误: 请指定模块ID")
+			fmt.Fprintf(msgOut, "用法: rmmp module %s <模块ID>\n", subCommand)
+			return
+		}
+		changeModuleState(subCommand, args[1])
+	case "help", "-h", "--help":
+		showModuleHelp()
 	default:
-		fmt.Printf("未知的模块子命令: %s\n", subCommand)
+		fmt.Fprintf(msgOut, "未知的模块子命令: %s\n", subCommand)
 		showModuleHelp()
 	}
 }
 
-// 安装模块的核心逻辑
-func installModule(zipFile string) {
-	// 检查zip文件是否存在
-	if !fileExists(zipFile) {
-		fmt.Printf("错误: 文件不存在: %s\n", zipFile)
+// handleInstallCommand 解析 module install 的参数
+func handleInstallCommand(args []string) {
+	var opts InstallOptions
+	var sources []string
+	keepGoing := false
+	dryRun := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(arg, "=")
+		switch name {
+		case "--on-conflict":
+			if !hasValue {
+				if i+1 >= len(args) {
+					fmt.Fprintln(msgOut, "错误: --on-conflict 需要一个参数 (ask, abort, continue, disable)")
+					return
+				}
+				i++
+				value = args[i]
+			}
+			switch value {
+			case ConflictAsk, ConflictAbort, ConflictContinue, ConflictDisable:
+				opts.ConflictPolicy = value
+			default:
+				fmt.Fprintf(msgOut, "错误: 未知的冲突处理策略: %s\n", value)
+				return
+			}
+		case "--skip-deps":
+			opts.SkipDeps = true
+		case "--keep-going", "-k":
+			keepGoing = true
+		case "--dry-run", "-n":
+			dryRun = true
+		default:
+			sources = append(sources, arg)
+		}
+	}
+
+	if len(sources) == 0 {
+		fmt.Fprintln(msgOut, "错误: 请指定要安装的模块")
+		fmt.Fprintln(msgOut, "用法: rmmp module install <zip|https链接|-|目录|tar.gz>... [--on-conflict ask|abort|continue|disable] [--skip-deps] [--keep-going] [--dry-run]")
+		return
+	}
+
+	sources, err := expandInstallArgs(sources)
+	if err != nil {
+		fmt.Fprintf(msgOut, "错误: %v\n", err)
+		return
+	}
+
+	if dryRun {
+		handleDryRunInstall(sources, opts)
 		return
 	}
 
-	// 检查文件扩展名
-	if !strings.HasSuffix(strings.ToLower(zipFile), ".zip") {
-		fmt.Printf("警告: 文件可能不是zip格式: %s\n", zipFile)
+	if len(sources) == 1 {
+		installModule(sources[0], opts)
+		return
 	}
 
+	rmmd := NewRMMD()
+	results := rmmd.InstallModules(sources, opts, keepGoing)
+	if machineOutput() {
+		if err := printData(results); err != nil {
+			fmt.Fprintf(msgOut, "❌ %v\n", err)
+		}
+		return
+	}
+	printInstallResults(results)
+}
+
+// 安装模块的核心逻辑
+func installModule(source string, opts InstallOptions) {
+	// 将链接、stdin、目录和 tar.gz 转换为本地zip
+	if opts.Origin == "" {
+		opts.Origin = source
+	}
+	zipFile, cleanup, err := resolveInstallSource(source, &opts)
+	if err != nil {
+		fmt.Fprintf(msgOut, "错误: %v\n", err)
+		return
+	}
+	defer cleanup()
+
 	// 获取绝对路径
 	absPath, err := filepath.Abs(zipFile)
 	if err != nil {
-		fmt.Printf("错误: 无法获取文件绝对路径: %v\n", err)
+		fmt.Fprintf(msgOut, "错误: 无法获取文件绝对路径: %v\n", err)
 		return
 	}
 
-	fmt.Printf("正在安装模块: %s\n", absPath)
-	fmt.Println("🔧 使用内置模块安装器...")
+	fmt.Fprintf(msgOut, "正在安装模块: %s\n", absPath)
+
+	// 守护进程无法询问用户，需要交互处理冲突时在本地安装
+	if opts.ConflictPolicy != "" && opts.ConflictPolicy != ConflictAsk {
+		if client := dialDaemon(); client != nil {
+			defer client.Close()
+			params := daemonInstallParams{
+				ZipPath:        absPath,
+				ConflictPolicy: opts.ConflictPolicy,
+				SkipDeps:       opts.SkipDeps,
+				Origin:         opts.Origin,
+				Source:         opts.Source,
+				ZipURL:         opts.ZipURL,
+				Proxy:          opts.Proxy,
+				Dependencies:   opts.Dependencies,
+			}
+			if err := client.Call("install", params, nil); err != nil {
+				fmt.Fprintf(msgOut, "❌ 模块安装失败: %v\n", err)
+				return
+			}
+			fmt.Fprintln(msgOut, "✅ 模块安装完成!")
+			return
+		}
+	}
+
+	fmt.Fprintln(msgOut, "🔧 使用内置模块安装器...")
 
 	// 使用内置的模块安装器
-	err = installModuleWithBuiltinInstaller(absPath)
+	err = installModuleWithBuiltinInstaller(absPath, opts)
 	if err != nil {
-		fmt.Printf("❌ 模块安装失败: %v\n", err)
-		return
+		fmt.Fprintf(msgOut, "❌ 模块安装失败: %v\n", err)
 	}
-
-	fmt.Println("✅ 模块安装完成!")
 }
 
 // installModuleWithBuiltinInstaller 使用内置安装器安装模块
-func installModuleWithBuiltinInstaller(zipPath string) error {
-	fmt.Println("📦 正在解析模块...")
+func installModuleWithBuiltinInstaller(zipPath string, opts InstallOptions) error {
+	fmt.Fprintln(msgOut, "📦 正在解析模块...")
 
 	// 使用 RMMD 内置安装器
 	rmmd := NewRMMD()
-	return rmmd.InstallModule(zipPath)
+	return rmmd.InstallModuleWithDeps(zipPath, opts)
 }
 
 // 检查文件是否存在
@@ -123,79 +335,221 @@ func fileExists(path string) bool {
 }
 
 // listModules 列出已安装的模块
-func listModules() {
+func listModules(opts ListOptions) {
+	if client := dialDaemon(); client != nil {
+		defer client.Close()
+		if err := listModulesFromDaemon(client, opts); err != nil {
+			fmt.Fprintf(msgOut, "❌ 列出模块失败: %v\n", err)
+		}
+		return
+	}
+
 	rmmd := NewRMMD()
-	err := rmmd.PrintModuleList()
+	err := rmmd.PrintModuleList(opts)
+	if err != nil {
+		fmt.Fprintf(msgOut, "❌ 列出模块失败: %v\n", err)
+	}
+}
+
+// listModulesFromDaemon 通过守护进程获取模块和更新状态，在本地过滤和打印
+func listModulesFromDaemon(client *DaemonClient, opts ListOptions) error {
+	var info DaemonInfo
+	if err := client.Call("ping", nil, &info); err != nil {
+		return err
+	}
+	var modules []ModuleInfo
+	if err := client.Call("list", nil, &modules); err != nil {
+		return err
+	}
+	var results []UpdateCheckResult
+	if err := client.Call("checkUpdates", nil, &results); err != nil {
+		return err
+	}
+	return printModuleList(info.RootEnv, modules, results, opts)
+}
+
+// changeModuleState 启用、禁用、卸载模块或撤销卸载
+// 守护进程运行时通过守护进程执行
+func changeModuleState(action, moduleID string) {
+	if action == "remove" {
+		action = "uninstall"
+	}
+
+	var result *ModuleActionResult
+	var err error
+	if client := dialDaemon(); client != nil {
+		defer client.Close()
+		err = client.Call(action, daemonModuleParams{ID: moduleID}, &result)
+	} else {
+		rmmd := NewRMMD()
+		switch action {
+		case "enable":
+			result, err = rmmd.EnableModule(moduleID)
+		case "disable":
+			result, err = rmmd.DisableModule(moduleID)
+		case "uninstall":
+			result, err = rmmd.UninstallModule(moduleID)
+		case "undo-uninstall":
+			result, err = rmmd.UndoUninstallModule(moduleID)
+		}
+	}
 	if err != nil {
-		fmt.Printf("❌ 列出模块失败: %v\n", err)
+		fmt.Fprintf(msgOut, "❌ 操作失败: %v\n", err)
+		return
+	}
+
+	if machineOutput() {
+		if err := printData(result); err != nil {
+			fmt.Fprintf(msgOut, "❌ %v\n", err)
+		}
+		return
+	}
+	printModuleActionResult(result)
+}
+
+// printModuleActionResult 打印模块操作结果
+func printModuleActionResult(result *ModuleActionResult) {
+	actionNames := map[string]string{
+		"enable":         "启用",
+		"disable":        "禁用",
+		"uninstall":      "卸载",
+		"undo-uninstall": "撤销卸载",
+	}
+	actionName := actionNames[result.Action]
+
+	if !result.Changed {
+		fmt.Fprintf(msgOut, "ℹ️  模块 %s 无需%s，状态未改变\n", result.ID, actionName)
+		return
+	}
+
+	fmt.Fprintf(msgOut, "✅ 模块 %s 已%s\n", result.ID, actionName)
+	if result.RebootRequired {
+		fmt.Fprintln(msgOut, "🔄 需要重启设备后生效")
 	}
 }
 
 // 处理搜索命令 (待开发)
 func handleSearchCommand(args []string) {
-	fmt.Println("🔍 搜索功能")
-	fmt.Println("此功能正在开发中，敬请期待！")
-	fmt.Println("")
-	fmt.Println("计划支持的功能:")
-	fmt.Println("  • 搜索在线模块仓库")
-	fmt.Println("  • 按名称/标签搜索模块")
-	fmt.Println("  • 显示模块详细信息")
-	fmt.Println("  • 直接下载安装模块")
+	fmt.Fprintln(msgOut, "🔍 搜索功能")
+	fmt.Fprintln(msgOut, "此功能正在开发中，敬请期待！")
+	fmt.Fprintln(msgOut, "")
+	fmt.Fprintln(msgOut, "计划支持的功能:")
+	fmt.Fprintln(msgOut, "  • 搜索在线模块仓库")
+	fmt.Fprintln(msgOut, "  • 按名称/标签搜索模块")
+	fmt.Fprintln(msgOut, "  • 显示模块详细信息")
+	fmt.Fprintln(msgOut, "  • 直接下载安装模块")
 
 	if len(args) > 0 {
-		fmt.Printf("您搜索的关键词: %s\n", strings.Join(args, " "))
+		fmt.Fprintf(msgOut, "您搜索的关键词: %s\n", strings.Join(args, " "))
 	}
 }
 
 // 显示主帮助信息
 func showHelp() {
-	fmt.Printf("rmmp - Root Module Manager Plus (rmm project) v%s\n", version)
-	fmt.Println("一个支持多种Root模块管理器的命令行工具")
-	fmt.Println("")
-	fmt.Println("用法:")
-	fmt.Println("  rmmp <命令> [选项...]")
-	fmt.Println("")
-	fmt.Println("可用命令:")
-	fmt.Println("  module    模块管理操作")
-	fmt.Println("  get       下载并安装GitHub仓库的模块")
-	fmt.Println("  proxy     GitHub代理管理")
-	fmt.Println("  search    搜索模块 (开发中)")
-	fmt.Println("  version   显示版本信息")
-	fmt.Println("  help      显示帮助信息")
-	fmt.Println("")
-	fmt.Println("示例:")
-	fmt.Println("  rmmp module install example.zip")
-	fmt.Println("  rmmp module list")
-	fmt.Println("  rmmp get username/repo")
-	fmt.Println("  rmmp get                    # 自我更新")
-	fmt.Println("  rmmp proxy list")
-	fmt.Println("  rmmp search keyword")
-	fmt.Println("  rmmp version")
-	fmt.Println("")
-	fmt.Println("获取特定命令的帮助:")
-	fmt.Println("  rmmp module help")
+	fmt.Fprintf(msgOut, "rmmp - Root Module Manager Plus (rmm project) v%s\n", version)
+	fmt.Fprintln(msgOut, "一个支持多种Root模块管理器的命令行工具")
+	fmt.Fprintln(msgOut, "")
+	fmt.Fprintln(msgOut, "用法:")
+	fmt.Fprintln(msgOut, "  rmmp [全局选项] <命令> [选项...]")
+	fmt.Fprintln(msgOut, "")
+	fmt.Fprintln(msgOut, "全局选项:")
+	fmt.Fprintln(msgOut, "  --sysroot <目录>          用指定目录代替 /data/adb (也可设置 RMMP_SYSROOT)")
+	fmt.Fprintln(msgOut, "  -o, --output <格式>       输出格式: table, json, yaml (也可设置 RMMP_OUTPUT)")
+	fmt.Fprintln(msgOut, "                            json/yaml 模式下数据输出到stdout，提示信息输出到stderr")
+	fmt.Fprintln(msgOut, "  --root-env <名称>         跳过自动检测，使用 Magisk、APatch、KernelSU 或 Native (也可设置 RMMP_ROOT_ENV)")
+	fmt.Fprintln(msgOut, "")
+	fmt.Fprintln(msgOut, "可用命令:")
+	fmt.Fprintln(msgOut, "  module    模块管理操作")
+	fmt.Fprintln(msgOut, "  get       下载并安装GitHub仓库的模块及其依赖 (--skip-deps 跳过依赖)")
+	fmt.Fprintln(msgOut, "  export    导出已安装模块的锁文件 (默认 rmmp.lock.json)")
+	fmt.Fprintln(msgOut, "  sync      按锁文件安装、禁用或删除模块 (--dry-run 仅显示计划, -y 不询问)")
+	fmt.Fprintln(msgOut, "  history   查看安装、升级、卸载、启用/禁用等操作记录 (--module, --since)")
+	fmt.Fprintln(msgOut, "  status    列出重启后才会生效的安装、升级、删除和启用/禁用")
+	fmt.Fprintln(msgOut, "  doctor    诊断Root环境、SELinux、缓存目录和网络问题")
+	fmt.Fprintln(msgOut, "  daemon    启动守护进程，通过Unix socket提供模块操作和变更通知")
+	fmt.Fprintln(msgOut, "  proxy     GitHub代理管理")
+	fmt.Fprintln(msgOut, "  search    搜索模块 (开发中)")
+	fmt.Fprintln(msgOut, "  version   显示版本信息")
+	fmt.Fprintln(msgOut, "  help      显示帮助信息")
+	fmt.Fprintln(msgOut, "")
+	fmt.Fprintln(msgOut, "示例:")
+	fmt.Fprintln(msgOut, "  rmmp module install example.zip")
+	fmt.Fprintln(msgOut, "  rmmp module list")
+	fmt.Fprintln(msgOut, "  rmmp module list -c --disabled --sort size")
+	fmt.Fprintln(msgOut, "  rmmp --sysroot ./backup/adb module list")
+	fmt.Fprintln(msgOut, "  rmmp -o json module list")
+	fmt.Fprintln(msgOut, "  rmmp get username/repo")
+	fmt.Fprintln(msgOut, "  rmmp get                    # 自我更新")
+	fmt.Fprintln(msgOut, "  rmmp export phones.lock.json")
+	fmt.Fprintln(msgOut, "  rmmp sync phones.lock.json --dry-run")
+	fmt.Fprintln(msgOut, "  rmmp history --since 24h")
+	fmt.Fprintln(msgOut, "  rmmp status")
+	fmt.Fprintln(msgOut, "  rmmp doctor")
+	fmt.Fprintln(msgOut, "  rmmp daemon help")
+	fmt.Fprintln(msgOut, "  rmmp proxy list")
+	fmt.Fprintln(msgOut, "  rmmp search keyword")
+	fmt.Fprintln(msgOut, "  rmmp version")
+	fmt.Fprintln(msgOut, "")
+	fmt.Fprintln(msgOut, "获取特定命令的帮助:")
+	fmt.Fprintln(msgOut, "  rmmp module help")
 }
 
 // 显示模块命令帮助
 func showModuleHelp() {
-	fmt.Println("rmmp module - 模块管理操作")
-	fmt.Println("")
-	fmt.Println("用法:")
-	fmt.Println("  rmmp module <子命令> [选项...]")
-	fmt.Println("")
-	fmt.Println("可用子命令:")
-	fmt.Println("  install <zip文件>   安装指定的模块zip文件")
-	fmt.Println("  list                列出已安装的模块")
-	fmt.Println("")
-	fmt.Println("特性:")
-	fmt.Println("  • 内置模块安装器，无需外部依赖")
-	fmt.Println("  • 支持多种Root环境 (KernelSU, APatch, Magisk)")
-	fmt.Println("  • 自动模块验证和冲突检测")
-	fmt.Println("")
-	fmt.Println("示例:")
-	fmt.Println("  rmmp module install /sdcard/module.zip")
-	fmt.Println("  rmmp module install ./local-module.zip")
-	fmt.Println("  rmmp module list")
+	fmt.Fprintln(msgOut, "rmmp module - 模块管理操作")
+	fmt.Fprintln(msgOut, "")
+	fmt.Fprintln(msgOut, "用法:")
+	fmt.Fprintln(msgOut, "  rmmp module <子命令> [选项...]")
+	fmt.Fprintln(msgOut, "")
+	fmt.Fprintln(msgOut, "可用子命令:")
+	fmt.Fprintln(msgOut, "  install <模块>...       安装模块: zip文件(支持通配符)、https链接、- (stdin)、模块目录或 .tar.gz")
+	fmt.Fprintln(msgOut, "      --on-conflict <策略>  文件冲突时: ask(询问), abort, continue, disable(禁用冲突模块)")
+	fmt.Fprintln(msgOut, "      --skip-deps          不安装 rmmproject.toml / update.json 中声明的依赖")
+	fmt.Fprintln(msgOut, "      --keep-going         批量安装时跳过校验或安装失败的模块，继续安装其余模块")
+	fmt.Fprintln(msgOut, "      --dry-run, -n        在临时目录中试运行 customize.sh，报告文件、权限、属性、sepolicy和执行的命令")
+	fmt.Fprintln(msgOut, "  list [关键词]           列出已安装的模块，关键词匹配ID、名称和描述")
+	fmt.Fprintln(msgOut, "      --enabled, --disabled  只显示已启用/已禁用的模块")
+	fmt.Fprintln(msgOut, "      --author <作者>      按作者过滤")
+	fmt.Fprintln(msgOut, "      --has-update         只显示有更新的模块")
+	fmt.Fprintln(msgOut, "      --has-webui          只显示带WebUI的模块")
+	fmt.Fprintln(msgOut, "      --sort <方式>        name, id, version, size (从大到小), installed-at (从新到旧)")
+	fmt.Fprintln(msgOut, "      --compact, -c        每个模块一行")
+	fmt.Fprintln(msgOut, "  info <模块ID>           显示模块详细信息和功能")
+	fmt.Fprintln(msgOut, "  outdated [--refresh]    列出有可用更新的模块")
+	fmt.Fprintln(msgOut, "  upgrade <模块ID>...     升级指定模块 (--all 升级全部, --skip-deps 不处理依赖)")
+	fmt.Fprintln(msgOut, "      --on-conflict <策略>  文件冲突时的处理，批量升级或非交互运行时默认 abort (跳过该模块)")
+	fmt.Fprintln(msgOut, "  lint <目录|zip>         按 Magisk 规则检查模块 (module.prop、updateJson、脚本权限)")
+	fmt.Fprintln(msgOut, "  rollback <模块ID>       回滚到安装/升级/卸载之前的版本 (--to 指定版本, --list 列出还原点)")
+	fmt.Fprintln(msgOut, "  hold [模块ID]...        锁定模块使其不被升级 (无参数时列出)")
+	fmt.Fprintln(msgOut, "  unhold <模块ID>...      解除锁定")
+	fmt.Fprintln(msgOut, "  backup <模块ID>...      备份模块及其配置 (--all 备份全部, --to 指定文件)")
+	fmt.Fprintln(msgOut, "  restore <备份文件>      从备份重新安装模块")
+	fmt.Fprintln(msgOut, "  export <模块ID>         将已安装的模块导出为可安装的zip (--to 指定文件)")
+	fmt.Fprintln(msgOut, "  uninstall <模块ID>      卸载模块（重启后生效）")
+	fmt.Fprintln(msgOut, "  undo-uninstall <模块ID> 撤销尚未生效的卸载")
+	fmt.Fprintln(msgOut, "  enable <模块ID>         启用模块")
+	fmt.Fprintln(msgOut, "  disable <模块ID>        禁用模块")
+	fmt.Fprintln(msgOut, "")
+	fmt.Fprintln(msgOut, "特性:")
+	fmt.Fprintln(msgOut, "  • 内置模块安装器，无需外部依赖")
+	fmt.Fprintln(msgOut, "  • 支持多种Root环境 (KernelSU, APatch, Magisk)")
+	fmt.Fprintln(msgOut, "  • 自动模块验证和冲突检测")
+	fmt.Fprintln(msgOut, "")
+	fmt.Fprintln(msgOut, "示例:")
+	fmt.Fprintln(msgOut, "  rmmp module install /sdcard/module.zip")
+	fmt.Fprintln(msgOut, "  rmmp module install ./local-module.zip")
+	fmt.Fprintln(msgOut, "  rmmp module install https://example.com/module.zip")
+	fmt.Fprintln(msgOut, "  rmmp module install ./build/")
+	fmt.Fprintln(msgOut, "  cat module.zip | rmmp module install -")
+	fmt.Fprintln(msgOut, "  rmmp module install ./out/*.zip --keep-going")
+	fmt.Fprintln(msgOut, "  rmmp module install third-party.zip --dry-run")
+	fmt.Fprintln(msgOut, "  rmmp module list")
+	fmt.Fprintln(msgOut, "  rmmp module outdated")
+	fmt.Fprintln(msgOut, "  rmmp module upgrade --all")
+	fmt.Fprintln(msgOut, "  rmmp module rollback example_module --to 1.2.0")
+	fmt.Fprintln(msgOut, "  rmmp module backup --all --to /sdcard/modules-backup.zip")
+	fmt.Fprintln(msgOut, "  rmmp module disable example_module")
+	fmt.Fprintln(msgOut, "  rmmp module uninstall example_module")
 }
 
 // 处理代理相关命令
@@ -213,66 +567,72 @@ func handleProxyCommand(args []string) {
 	case "list", "ls":
 		err := gpm.ListProxies()
 		if err != nil {
-			fmt.Printf("❌ 获取代理列表失败: %v\n", err)
+			fmt.Fprintf(msgOut, "❌ 获取代理列表失败: %v\n", err)
 		}
 	case "best":
 		bestProxy, err := gpm.GetBestProxy()
 		if err != nil {
-			fmt.Printf("❌ 获取最佳代理失败: %v\n", err)
+			fmt.Fprintf(msgOut, "❌ 获取最佳代理失败: %v\n", err)
+			return
+		}
+		if machineOutput() {
+			if err := printData(bestProxy); err != nil {
+				fmt.Fprintf(msgOut, "❌ %v\n", err)
+			}
 			return
 		}
-		fmt.Printf("⭐ 最佳GitHub代理: %s\n", bestProxy.URL)
-		fmt.Printf("   服务商: %s\n", bestProxy.Server)
-		fmt.Printf("   IP地址: %s\n", bestProxy.IP)
-		fmt.Printf("   延迟: %dms\n", bestProxy.Latency)
-		fmt.Printf("   速度: %.2fMB/s\n", bestProxy.Speed)
+		fmt.Fprintf(msgOut, "⭐ 最佳GitHub代理: %s\n", bestProxy.URL)
+		fmt.Fprintf(msgOut, "   服务商: %s\n", bestProxy.Server)
+		fmt.Fprintf(msgOut, "   IP地址: %s\n", bestProxy.IP)
+		fmt.Fprintf(msgOut, "   延迟: %dms\n", bestProxy.Latency)
+		fmt.Fprintf(msgOut, "   速度: %.2fMB/s\n", bestProxy.Speed)
 	case "update":
 		gpm.ClearCache()
 		proxies, err := gpm.GetProxies()
 		if err != nil {
-			fmt.Printf("❌ 更新代理数据失败: %v\n", err)
+			fmt.Fprintf(msgOut, "❌ 更新代理数据失败: %v\n", err)
 			return
 		}
-		fmt.Printf("✅ 代理数据已更新，共获取 %d 个代理\n", len(proxies))
+		fmt.Fprintf(msgOut, "✅ 代理数据已更新，共获取 %d 个代理\n", len(proxies))
 	case "clear":
 		err := gpm.ClearCache()
 		if err != nil {
-			fmt.Printf("❌ 清除缓存失败: %v\n", err)
+			fmt.Fprintf(msgOut, "❌ 清除缓存失败: %v\n", err)
 		}
 	case "help", "-h", "--help":
 		showProxyHelp()
 	default:
-		fmt.Printf("未知的代理子命令: %s\n", subCommand)
+		fmt.Fprintf(msgOut, "未知的代理子命令: %s\n", subCommand)
 		showProxyHelp()
 	}
 }
 
 // 显示代理命令帮助
 func showProxyHelp() {
-	fmt.Println("rmmp proxy - GitHub代理管理")
-	fmt.Println("")
-	fmt.Println("用法:")
-	fmt.Println("  rmmp proxy <子命令> [选项...]")
-	fmt.Println("")
-	fmt.Println("可用子命令:")
-	fmt.Println("  list, ls      列出所有可用的GitHub代理")
-	fmt.Println("  best          显示推荐的最佳代理")
-	fmt.Println("  update        强制更新代理数据")
-	fmt.Println("  clear         清除缓存文件")
-	fmt.Println("  help          显示帮助信息")
-	fmt.Println("")
-	fmt.Println("特性:")
-	fmt.Println("  • 自动缓存代理数据（10小时有效期）")
-	fmt.Println("  • 智能推荐最佳代理（综合延迟和速度）")
-	fmt.Println("  • 支持强制更新和缓存管理")
-	fmt.Println("  • 跨平台支持，自动选择合适的缓存路径")
-	fmt.Println("")
-	fmt.Println("示例:")
-	fmt.Println("  rmmp proxy list          # 列出所有代理")
-	fmt.Println("  rmmp proxy best          # 显示最佳代理")
-	fmt.Println("  rmmp proxy update        # 强制更新数据")
-	fmt.Println("  rmmp proxy clear         # 清除缓存")
-	fmt.Println("") // 显示当前平台的缓存路径
+	fmt.Fprintln(msgOut, "rmmp proxy - GitHub代理管理")
+	fmt.Fprintln(msgOut, "")
+	fmt.Fprintln(msgOut, "用法:")
+	fmt.Fprintln(msgOut, "  rmmp proxy <子命令> [选项...]")
+	fmt.Fprintln(msgOut, "")
+	fmt.Fprintln(msgOut, "可用子命令:")
+	fmt.Fprintln(msgOut, "  list, ls      列出所有可用的GitHub代理")
+	fmt.Fprintln(msgOut, "  best          显示推荐的最佳代理")
+	fmt.Fprintln(msgOut, "  update        强制更新代理数据")
+	fmt.Fprintln(msgOut, "  clear         清除缓存文件")
+	fmt.Fprintln(msgOut, "  help          显示帮助信息")
+	fmt.Fprintln(msgOut, "")
+	fmt.Fprintln(msgOut, "特性:")
+	fmt.Fprintln(msgOut, "  • 自动缓存代理数据（10小时有效期）")
+	fmt.Fprintln(msgOut, "  • 智能推荐最佳代理（综合延迟和速度）")
+	fmt.Fprintln(msgOut, "  • 支持强制更新和缓存管理")
+	fmt.Fprintln(msgOut, "  • 跨平台支持，自动选择合适的缓存路径")
+	fmt.Fprintln(msgOut, "")
+	fmt.Fprintln(msgOut, "示例:")
+	fmt.Fprintln(msgOut, "  rmmp proxy list          # 列出所有代理")
+	fmt.Fprintln(msgOut, "  rmmp proxy best          # 显示最佳代理")
+	fmt.Fprintln(msgOut, "  rmmp proxy update        # 强制更新数据")
+	fmt.Fprintln(msgOut, "  rmmp proxy clear         # 清除缓存")
+	fmt.Fprintln(msgOut, "") // 显示当前平台的缓存路径
 	gpm := NewGitHubProxyManager()
-	fmt.Printf("缓存文件位置: %s\n", gpm.GetCacheFilePath())
+	fmt.Fprintf(msgOut, "缓存文件位置: %s\n", gpm.GetCacheFilePath())
 }
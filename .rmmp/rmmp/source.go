@@ -0,0 +1,270 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// 安装脚本在zip中的位置
+const updateBinaryPath = "META-INF/com/google/android/update-binary"
+
+// resolveInstallSource 将 module install 的参数转换为本地zip文件
+// 支持本地zip、https链接、- (从stdin读取)、解压后的模块目录以及 .tar.gz
+// 返回的 cleanup 用于删除临时文件，安装结束后调用
+func resolveInstallSource(source string, opts *InstallOptions) (string, func(), error) {
+	noop := func() {}
+
+	switch {
+	case source == "-":
+		zipPath, err := saveStdin()
+		if err != nil {
+			return "", noop, err
+		}
+		return zipPath, func() { os.Remove(zipPath) }, nil
+
+	case strings.HasPrefix(source, "https://"):
+		zipPath, proxy, err := downloadInstallURL(source)
+		if err != nil {
+			return "", noop, err
+		}
+		opts.ZipURL = source
+		opts.Proxy = proxy
+		return zipPath, noop, nil
+
+	case strings.HasPrefix(source, "http://"):
+		return "", noop, fmt.Errorf("只支持 https 链接: %s", source)
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return "", noop, fmt.Errorf("文件不存在: %s", source)
+	}
+
+	lower := strings.ToLower(source)
+	switch {
+	case info.IsDir():
+		fmt.Fprintf(msgOut, "📦 正在打包模块目录: %s\n", source)
+		zipPath, err := packModuleDir(source)
+		if err != nil {
+			return "", noop, err
+		}
+		return zipPath, func() { os.Remove(zipPath) }, nil
+
+	case strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz"):
+		fmt.Fprintf(msgOut, "📦 正在转换 tar.gz: %s\n", source)
+		zipPath, err := convertTarGz(source)
+		if err != nil {
+			return "", noop, err
+		}
+		return zipPath, func() { os.Remove(zipPath) }, nil
+	}
+
+	if !strings.HasSuffix(lower, ".zip") {
+		fmt.Fprintf(msgOut, "警告: 文件可能不是zip格式: %s\n", source)
+	}
+	return source, noop, nil
+}
+
+// saveStdin 将stdin中的zip保存到临时文件
+func saveStdin() (string, error) {
+	file, err := os.CreateTemp("", "rmmp-stdin-*.zip")
+	if err != nil {
+		return "", fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	defer file.Close()
+
+	fmt.Fprintln(msgOut, "📥 正在从stdin读取模块...")
+	n, err := io.Copy(file, os.Stdin)
+	if err == nil && n == 0 {
+		err = fmt.Errorf("没有数据")
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("读取stdin失败: %v", err)
+	}
+	return file.Name(), nil
+}
+
+// downloadInstallURL 下载链接指向的模块，保存在下载缓存目录中
+// 返回下载时使用的代理，未使用代理时为空
+func downloadInstallURL(url string) (string, string, error) {
+	md := NewModuleDownloader()
+	if err := os.MkdirAll(md.cacheDir, 0755); err != nil {
+		return "", "", fmt.Errorf("创建下载目录失败: %v", err)
+	}
+
+	localPath := filepath.Join(md.cacheDir, "url_"+urlHash(url)+".zip")
+
+	fmt.Fprintf(msgOut, "🔄 正在下载模块: %s\n", url)
+	if _, err := md.downloadURL(url, localPath); err != nil {
+		os.Remove(localPath)
+		return "", "", fmt.Errorf("下载模块失败: %v", err)
+	}
+	return localPath, md.lastProxy, nil
+}
+
+// packModuleDir 将解压后的模块目录打包为临时zip
+// 跳过状态标记和 .git，目录中没有安装脚本时补上标准的 update-binary
+func packModuleDir(dir string) (string, error) {
+	if !fileExists(filepath.Join(dir, "module.prop")) {
+		return "", fmt.Errorf("目录中没有 module.prop: %s", dir)
+	}
+
+	file, err := os.CreateTemp("", "rmmp-dir-*.zip")
+	if err != nil {
+		return "", fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	defer file.Close()
+
+	skip := func(rel string) bool {
+		return isModuleStateMarker(rel) || rel == ".git"
+	}
+
+	w := zip.NewWriter(file)
+	_, err = addDirToZip(w, dir, "", skip)
+	if err == nil && !fileExists(filepath.Join(dir, filepath.FromSlash(updateBinaryPath))) {
+		err = writeModuleInstaller(w)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("打包模块目录失败: %v", err)
+	}
+	return file.Name(), nil
+}
+
+// convertTarGz 将 .tar.gz 转换为临时zip
+// 如果模块文件都在同一个顶层目录中（如GitHub生成的源码包），去掉这一层目录；状态标记同样跳过
+func convertTarGz(tarPath string) (string, error) {
+	var names []string
+	err := walkTarGz(tarPath, func(header *tar.Header, r io.Reader) error {
+		names = append(names, header.Name)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	prefix := tarModuleRoot(names)
+
+	file, err := os.CreateTemp("", "rmmp-tar-*.zip")
+	if err != nil {
+		return "", fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	defer file.Close()
+
+	w := zip.NewWriter(file)
+	hasInstaller := false
+	err = walkTarGz(tarPath, func(header *tar.Header, r io.Reader) error {
+		name := strings.TrimPrefix(strings.TrimPrefix(header.Name, "./"), prefix)
+		if name == "" || name == "/" || isModuleStateMarker(name) {
+			return nil
+		}
+		if name == updateBinaryPath {
+			hasInstaller = true
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir, tar.TypeReg, tar.TypeSymlink:
+		default:
+			fmt.Fprintf(msgOut, "⚠️  跳过不支持的条目: %s\n", header.Name)
+			return nil
+		}
+
+		zh, err := zip.FileInfoHeader(header.FileInfo())
+		if err != nil {
+			return err
+		}
+		zh.Name = name
+		if header.Typeflag == tar.TypeDir {
+			zh.Name = strings.TrimSuffix(name, "/") + "/"
+			_, err = w.CreateHeader(zh)
+			return err
+		}
+		zh.Method = zip.Deflate
+
+		fw, err := w.CreateHeader(zh)
+		if err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeSymlink {
+			_, err = io.WriteString(fw, header.Linkname)
+			return err
+		}
+		_, err = io.Copy(fw, r)
+		return err
+	})
+	if err == nil && !hasInstaller {
+		err = writeModuleInstaller(w)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("转换 tar.gz 失败: %v", err)
+	}
+	return file.Name(), nil
+}
+
+// walkTarGz 依次处理 .tar.gz 中的每个条目
+func walkTarGz(tarPath string, fn func(header *tar.Header, r io.Reader) error) error {
+	file, err := os.Open(tarPath)
+	if err != nil {
+		return fmt.Errorf("无法打开文件: %v", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("无法解压 gzip: %v", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("读取 tar 失败: %v", err)
+		}
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		if err := fn(header, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// tarModuleRoot 返回需要去掉的顶层目录前缀（带 /），module.prop 在根目录时返回空
+func tarModuleRoot(names []string) string {
+	for _, name := range names {
+		if strings.TrimPrefix(name, "./") == "module.prop" {
+			return ""
+		}
+	}
+	for _, name := range names {
+		name = strings.TrimPrefix(name, "./")
+		if path.Base(name) == "module.prop" && strings.Count(name, "/") == 1 {
+			prefix := path.Dir(name) + "/"
+			for _, other := range names {
+				if !strings.HasPrefix(strings.TrimPrefix(other, "./"), prefix) && strings.TrimPrefix(other, "./") != strings.TrimSuffix(prefix, "/") {
+					return ""
+				}
+			}
+			return prefix
+		}
+	}
+	return ""
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PendingChange 重启后才会生效的更改
+type PendingChange struct {
+	ID          string `json:"id"`
+	Change      string `json:"change"` // install, upgrade, downgrade, reinstall, update, remove, enable, disable
+	FromVersion string `json:"fromVersion,omitempty"`
+	ToVersion   string `json:"toVersion,omitempty"`
+	Staged      bool   `json:"staged"` // 文件在 modules_update 中等待合并
+}
+
+// StatusReport 待生效更改的汇总
+type StatusReport struct {
+	RootEnv        string          `json:"rootEnv"`
+	BootTime       *time.Time      `json:"bootTime,omitempty"`
+	Changes        []PendingChange `json:"changes"`
+	RebootRequired bool            `json:"rebootRequired"`
+}
+
+// 会替换模块文件的操作
+var installActions = map[string]bool{
+	"install":   true,
+	"upgrade":   true,
+	"downgrade": true,
+	"reinstall": true,
+}
+
+// bootTime 从 /proc/stat 的 btime 读取本次开机时间
+func bootTime() (time.Time, error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "btime "); ok {
+			seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(seconds, 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("/proc/stat 中没有 btime")
+}
+
+// PendingChanges 汇总重启后才会生效的更改
+// 来源有三处: modules_update 中暂存的模块、模块目录中的 update/remove 标记，
+// 以及本次开机后的操作历史（直接安装到 modules 的模块和启用/禁用）。
+// sysroot 模式下开机时间没有意义，不读取操作历史
+func (r *RMMD) PendingChanges() (*StatusReport, error) {
+	report := &StatusReport{RootEnv: r.getRootEnvName(), Changes: []PendingChange{}}
+
+	modules, err := r.ListModules()
+	if err != nil {
+		return nil, err
+	}
+	installed := make(map[string]ModuleInfo)
+	// Magisk 暂存更新时在模块目录中创建的 update 标记
+	// ModuleInfo.Update 在只有 modules_update 中的暂存模块时同样为 true，不能用来判断
+	updateMarked := make(map[string]bool)
+	for _, module := range modules {
+		installed[module.ID] = module
+		updateMarked[module.ID] = r.fileExists(filepath.Join(moduleDir(module), "update"))
+	}
+
+	// 本次开机后每个模块最后一次成功的安装操作，以及启用/禁用操作
+	lastInstall := make(map[string]HistoryEntry)
+	toggles := make(map[string][]HistoryEntry)
+	if sysroot == "" {
+		if boot, err := bootTime(); err == nil {
+			report.BootTime = &boot
+			entries, err := loadHistory(HistoryFilter{Since: boot})
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				if entry.Outcome != OutcomeSuccess {
+					continue
+				}
+				switch {
+				case installActions[entry.Action]:
+					lastInstall[entry.ID] = entry
+				case entry.Action == "enable" || entry.Action == "disable":
+					toggles[entry.ID] = append(toggles[entry.ID], entry)
+				}
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	staged, err := r.stagedModules()
+	if err != nil {
+		return nil, err
+	}
+	for _, module := range staged {
+		change := PendingChange{ID: module.ID, Change: "install", ToVersion: module.Version, Staged: true}
+		current, exists := installed[module.ID]
+		if entry, ok := lastInstall[module.ID]; ok {
+			change.Change = entry.Action
+			change.FromVersion = entry.FromVersion
+		} else if exists && updateMarked[module.ID] {
+			// Magisk 暂存时已经把新的 module.prop 复制到模块目录，无法得知旧版本
+			change.Change = "update"
+		} else if exists {
+			change.FromVersion = current.Version
+			switch {
+			case module.VersionCode > current.VersionCode:
+				change.Change = "upgrade"
+			case module.VersionCode < current.VersionCode:
+				change.Change = "downgrade"
+			default:
+				change.Change = "reinstall"
+			}
+		}
+		report.Changes = append(report.Changes, change)
+		seen[module.ID] = true
+	}
+
+	for _, module := range modules {
+		if seen[module.ID] {
+			continue
+		}
+		if entry, ok := lastInstall[module.ID]; ok {
+			report.Changes = append(report.Changes, PendingChange{
+				ID:          module.ID,
+				Change:      entry.Action,
+				FromVersion: entry.FromVersion,
+				ToVersion:   entry.ToVersion,
+			})
+		} else if updateMarked[module.ID] {
+			report.Changes = append(report.Changes, PendingChange{ID: module.ID, Change: "update", ToVersion: module.Version})
+		}
+	}
+
+	for _, module := range modules {
+		if module.Remove {
+			report.Changes = append(report.Changes, PendingChange{ID: module.ID, Change: "remove", FromVersion: module.Version})
+			continue
+		}
+		// 开机时的状态与第一次操作的结果相反，与当前状态不同时才需要重启
+		entries := toggles[module.ID]
+		if len(entries) == 0 || seen[module.ID] {
+			continue
+		}
+		enabledAtBoot := entries[0].Action == "disable"
+		if enabledAtBoot == module.Enabled {
+			continue
+		}
+		change := "disable"
+		if module.Enabled {
+			change = "enable"
+		}
+		report.Changes = append(report.Changes, PendingChange{ID: module.ID, Change: change})
+	}
+
+	sort.SliceStable(report.Changes, func(i, j int) bool {
+		return report.Changes[i].ID < report.Changes[j].ID
+	})
+	report.RebootRequired = len(report.Changes) > 0
+	return report, nil
+}
+
+// stagedModules 列出 modules_update 中等待合并的模块
+func (r *RMMD) stagedModules() ([]ModuleInfo, error) {
+	var staged []ModuleInfo
+	if !r.dirExists(modulesUpdateDir) {
+		return staged, nil
+	}
+
+	entries, err := os.ReadDir(hostPath(modulesUpdateDir))
+	if err != nil {
+		return nil, fmt.Errorf("读取暂存目录失败: %v", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		module, err := r.parseMagiskModule(entry.Name(), filepath.Join(modulesUpdateDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		staged = append(staged, *module)
+	}
+	return staged, nil
+}
+
+// printStatus 打印待生效的更改
+func printStatus(report *StatusReport) {
+	if len(report.Changes) == 0 {
+		fmt.Fprintln(msgOut, "✅ 没有待生效的更改")
+		return
+	}
+
+	icons := map[string]string{
+		"install":   "📦",
+		"upgrade":   "⬆️ ",
+		"downgrade": "⬇️ ",
+		"reinstall": "🔁",
+		"update":    "🔄",
+		"remove":    "🗑️ ",
+		"enable":    "🟢",
+		"disable":   "🔴",
+	}
+
+	fmt.Fprintf(msgOut, "📋 待生效的更改 (%s) - 共 %d 项:\n", report.RootEnv, len(report.Changes))
+	fmt.Fprintln(msgOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	for _, change := range report.Changes {
+		versions := change.ToVersion
+		if change.FromVersion != "" && change.ToVersion != "" {
+			versions = change.FromVersion + " → " + change.ToVersion
+		} else if change.FromVersion != "" {
+			versions = change.FromVersion
+		}
+		line := fmt.Sprintf("%s %-10s %-24s %s", icons[change.Change], change.Change, change.ID, versions)
+		if change.Staged {
+			line += " (已暂存)"
+		}
+		fmt.Fprintln(msgOut, strings.TrimRight(line, " "))
+	}
+	fmt.Fprintln(msgOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Fprintln(msgOut, "🔄 需要重启设备后生效")
+}
+
+// handleStatusCommand 处理 status 命令
+func handleStatusCommand() {
+	rmmd := NewRMMD()
+	report, err := rmmd.PendingChanges()
+	if err != nil {
+		fmt.Fprintf(msgOut, "❌ 获取状态失败: %v\n", err)
+		return
+	}
+
+	if machineOutput() {
+		if err := printData(report); err != nil {
+			fmt.Fprintf(msgOut, "❌ %v\n", err)
+		}
+		return
+	}
+	printStatus(report)
+}
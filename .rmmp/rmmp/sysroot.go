@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// 设备上的 adb 数据目录
+	deviceAdbDir = "/data/adb"
+	// 指定 sysroot 的环境变量
+	sysrootEnv = "RMMP_SYSROOT"
+)
+
+// sysroot 替代 /data/adb 的本地目录，为空时直接访问设备路径
+// 可通过 --sysroot 参数或 RMMP_SYSROOT 环境变量设置，
+// 用于在电脑上读取解压的设备备份或测试目录
+var sysroot = os.Getenv(sysrootEnv)
+
+// setSysroot 设置 sysroot 目录
+func setSysroot(dir string) error {
+	if dir == "" {
+		sysroot = ""
+		return nil
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	sysroot = absDir
+	return nil
+}
+
+// hostPath 将 /data/adb 下的设备路径映射到 sysroot 中，其他路径原样返回
+func hostPath(path string) string {
+	if sysroot == "" {
+		return path
+	}
+
+	if path == deviceAdbDir {
+		return sysroot
+	}
+	if strings.HasPrefix(path, deviceAdbDir+"/") {
+		return filepath.Join(sysroot, strings.TrimPrefix(path, deviceAdbDir+"/"))
+	}
+	return path
+}
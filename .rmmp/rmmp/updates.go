@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	// 更新检查结果缓存有效期
+	updateCacheValidDuration = 1 * time.Hour
+	// 更新检查失败结果的缓存有效期
+	updateCacheErrorDuration = 10 * time.Minute
+	// 同时进行的更新检查数量上限
+	updateCheckConcurrency = 8
+)
+
+// UpdateCheckResult 表示单个模块的更新检查结果
+type UpdateCheckResult struct {
+	ID                string   `json:"id"`
+	Name              string   `json:"name"`
+	Version           string   `json:"version"`
+	VersionCode       int      `json:"versionCode"`
+	LatestVersion     string   `json:"latestVersion"`
+	LatestVersionCode int      `json:"latestVersionCode"`
+	UpdateJSON        string   `json:"updateJson"`
+	ZipURL            string   `json:"zipUrl"`
+	Dependencies      []string `json:"dependencies,omitempty"`
+	Outdated          bool     `json:"outdated"`
+	Error             string   `json:"error,omitempty"`
+}
+
+// updateCacheEntry 更新检查缓存项
+type updateCacheEntry struct {
+	Info      *UpdateInfo `json:"info,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	FetchedAt time.Time   `json:"fetched_at"`
+}
+
+// UpdateChecker 模块更新检查器
+type UpdateChecker struct {
+	md          *ModuleDownloader
+	cacheFile   string
+	concurrency int
+	refresh     bool
+
+	mu    sync.Mutex
+	cache map[string]updateCacheEntry
+}
+
+// getUpdateCacheFilePath 获取更新检查缓存文件路径，根据平台自动选择
+func getUpdateCacheFilePath() string {
+	if runtime.GOOS == "android" {
+		return "/data/adb/modules/rmmp/update_cache.json"
+	} else {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "./update_cache.json"
+		}
+		return filepath.Join(homeDir, "data", "adb", ".rmm", "CACHE", "update_cache.json")
+	}
+}
+
+// NewUpdateChecker 创建新的更新检查器
+func NewUpdateChecker() *UpdateChecker {
+	md := NewModuleDownloader()
+	md.quiet = true
+
+	return &UpdateChecker{
+		md:          md,
+		cacheFile:   getUpdateCacheFilePath(),
+		concurrency: updateCheckConcurrency,
+	}
+}
+
+// CheckModules 并发检查模块更新，结果顺序与传入的模块顺序一致
+// 没有 updateJson 的模块不会出现在结果中
+func (uc *UpdateChecker) CheckModules(modules []ModuleInfo) []UpdateCheckResult {
+	uc.loadCache()
+
+	var candidates []ModuleInfo
+	for _, module := range modules {
+		if module.UpdateJSON != "" {
+			candidates = append(candidates, module)
+		}
+	}
+
+	results := make([]UpdateCheckResult, len(candidates))
+	sem := make(chan struct{}, uc.concurrency)
+	var wg sync.WaitGroup
+
+	for i, module := range candidates {
+		wg.Add(1)
+		go func(i int, module ModuleInfo) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = uc.checkModule(module)
+		}(i, module)
+	}
+	wg.Wait()
+
+	if err := uc.saveCache(); err != nil {
+		fmt.Fprintf(msgOut, "⚠️  保存更新检查缓存失败: %v\n", err)
+	}
+
+	return results
+}
+
+// checkModule 检查单个模块的更新
+func (uc *UpdateChecker) checkModule(module ModuleInfo) UpdateCheckResult {
+	result := UpdateCheckResult{
+		ID:          module.ID,
+		Name:        module.Name,
+		Version:     module.Version,
+		VersionCode: module.VersionCode,
+		UpdateJSON:  module.UpdateJSON,
+	}
+
+	info, err := uc.fetch(module.UpdateJSON)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.LatestVersion = info.Version
+	result.LatestVersionCode = info.VersionCode
+	result.ZipURL = info.ZipURL
+	result.Dependencies = info.Dependencies
+	result.Outdated = info.VersionCode > module.VersionCode
+	return result
+}
+
+// fetch 获取update.json，优先使用缓存
+func (uc *UpdateChecker) fetch(url string) (*UpdateInfo, error) {
+	uc.mu.Lock()
+	entry, ok := uc.cache[url]
+	uc.mu.Unlock()
+
+	if ok && !uc.refresh && entry.valid() {
+		if entry.Error != "" {
+			return nil, fmt.Errorf("%s", entry.Error)
+		}
+		return entry.Info, nil
+	}
+
+	info, err := uc.md.fetchUpdateJSON(url)
+	entry = updateCacheEntry{Info: info, FetchedAt: time.Now()}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	uc.mu.Lock()
+	uc.cache[url] = entry
+	uc.mu.Unlock()
+
+	return info, err
+}
+
+// valid 判断缓存项是否仍然有效
+func (e updateCacheEntry) valid() bool {
+	ttl := updateCacheValidDuration
+	if e.Error != "" {
+		ttl = updateCacheErrorDuration
+	}
+	return time.Since(e.FetchedAt) <= ttl
+}
+
+// loadCache 读取更新检查缓存
+func (uc *UpdateChecker) loadCache() {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	uc.cache = make(map[string]updateCacheEntry)
+
+	data, err := os.ReadFile(uc.cacheFile)
+	if err != nil {
+		return
+	}
+
+	if err := json.Unmarshal(data, &uc.cache); err != nil {
+		fmt.Fprintf(msgOut, "⚠️  读取更新检查缓存失败: %v\n", err)
+		uc.cache = make(map[string]updateCacheEntry)
+	}
+}
+
+// saveCache 保存更新检查缓存
+func (uc *UpdateChecker) saveCache() error {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(uc.cacheFile), 0755); err != nil {
+		return fmt.Errorf("创建缓存目录失败: %v", err)
+	}
+
+	data, err := json.MarshalIndent(uc.cache, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化缓存数据失败: %v", err)
+	}
+
+	return os.WriteFile(uc.cacheFile, data, 0644)
+}
+
+// ApplyUpdateStatus 根据检查结果设置模块的 Outdated 字段
+func ApplyUpdateStatus(modules []ModuleInfo, results []UpdateCheckResult) {
+	outdated := make(map[string]bool)
+	for _, result := range results {
+		outdated[result.ID] = result.Outdated
+	}
+
+	for i := range modules {
+		modules[i].Outdated = outdated[modules[i].ID]
+	}
+}
+
+// PrintOutdatedModules 检查并打印有可用更新的模块
+func (r *RMMD) PrintOutdatedModules(refresh bool) error {
+	modules, err := r.ListModules()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(msgOut, "🔍 正在检查模块更新...")
+	uc := NewUpdateChecker()
+	uc.refresh = refresh
+	return printOutdatedResults(uc.CheckModules(modules))
+}
+
+// printOutdatedResults 打印更新检查结果
+func printOutdatedResults(results []UpdateCheckResult) error {
+	if machineOutput() {
+		return printData(results)
+	}
+
+	var outdated, failed []UpdateCheckResult
+	for _, result := range results {
+		if result.Error != "" {
+			failed = append(failed, result)
+		} else if result.Outdated {
+			outdated = append(outdated, result)
+		}
+	}
+
+	if len(outdated) == 0 {
+		fmt.Fprintf(msgOut, "✅ 所有模块均为最新版本 (已检查 %d 个)\n", len(results))
+	} else {
+		fmt.Fprintf(msgOut, "\n🔄 有可用更新的模块 (共 %d 个):\n", len(outdated))
+		fmt.Fprintln(msgOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		fmt.Fprintf(msgOut, "%-25s %-20s %-20s\n", "模块ID", "当前版本", "最新版本")
+		fmt.Fprintln(msgOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		for _, result := range outdated {
+			fmt.Fprintf(msgOut, "%-25s %-20s %-20s\n", result.ID,
+				fmt.Sprintf("%s (%d)", result.Version, result.VersionCode),
+				fmt.Sprintf("%s (%d)", result.LatestVersion, result.LatestVersionCode))
+		}
+		fmt.Fprintln(msgOut, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	}
+
+	if len(failed) > 0 {
+		fmt.Fprintf(msgOut, "\n⚠️  %d 个模块检查失败:\n", len(failed))
+		for _, result := range failed {
+			fmt.Fprintf(msgOut, "   %s: %s\n", result.ID, result.Error)
+		}
+	}
+
+	return nil
+}
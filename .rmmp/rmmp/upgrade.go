@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// UpgradeResult 表示单个模块的升级结果
+type UpgradeResult struct {
+	ID          string `json:"id"`
+	FromVersion string `json:"fromVersion"`
+	ToVersion   string `json:"toVersion,omitempty"`
+	Status      string `json:"status"` // upgraded, failed, skipped
+	Reason      string `json:"reason,omitempty"`
+}
+
+// getHoldFilePath 获取锁定（不升级）模块列表文件路径
+func getHoldFilePath() string {
+	return filepath.Join(getDataDir(), "holds.json")
+}
+
+// loadHolds 读取锁定的模块列表
+func loadHolds() (map[string]bool, error) {
+	holds := make(map[string]bool)
+
+	data, err := os.ReadFile(getHoldFilePath())
+	if os.IsNotExist(err) {
+		return holds, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取锁定列表失败: %v", err)
+	}
+
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, fmt.Errorf("解析锁定列表失败: %v", err)
+	}
+
+	for _, id := range ids {
+		holds[id] = true
+	}
+	return holds, nil
+}
+
+// sortedHoldIDs 按字母顺序返回锁定的模块ID
+func sortedHoldIDs(holds map[string]bool) []string {
+	ids := make([]string, 0, len(holds))
+	for id := range holds {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// saveHolds 保存锁定的模块列表
+func saveHolds(holds map[string]bool) error {
+	data, err := json.MarshalIndent(sortedHoldIDs(holds), "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化锁定列表失败: %v", err)
+	}
+
+	holdFile := getHoldFilePath()
+	if err := os.MkdirAll(filepath.Dir(holdFile), 0755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %v", err)
+	}
+
+	if err := os.WriteFile(holdFile, data, 0644); err != nil {
+		return fmt.Errorf("写入锁定列表失败: %v", err)
+	}
+	return nil
+}
+
+// handleHoldCommand 锁定或解锁模块，锁定的模块不会被 upgrade 升级
+func handleHoldCommand(hold bool, ids []string) {
+	holds, err := loadHolds()
+	if err != nil {
+		fmt.Fprintf(msgOut, "❌ %v\n", err)
+		return
+	}
+
+	if len(ids) == 0 {
+		if machineOutput() {
+			if err := printData(sortedHoldIDs(holds)); err != nil {
+				fmt.Fprintf(msgOut, "❌ %v\n", err)
+			}
+			return
+		}
+		if len(holds) == 0 {
+			fmt.Fprintln(msgOut, "📌 当前没有锁定的模块")
+			return
+		}
+		fmt.Fprintf(msgOut, "📌 已锁定的模块 (共 %d 个):\n", len(holds))
+		for _, id := range sortedHoldIDs(holds) {
+			fmt.Fprintf(msgOut, "   %s\n", id)
+		}
+		return
+	}
+
+	for _, id := range ids {
+		if !moduleIDPattern.MatchString(id) {
+			fmt.Fprintf(msgOut, "❌ 无效的模块ID: %s\n", id)
+			return
+		}
+		if hold {
+			holds[id] = true
+		} else {
+			delete(holds, id)
+		}
+	}
+
+	if err := saveHolds(holds); err != nil {
+		fmt.Fprintf(msgOut, "❌ %v\n", err)
+		return
+	}
+
+	if hold {
+		fmt.Fprintf(msgOut, "📌 已锁定: %s\n", strings.Join(ids, ", "))
+	} else {
+		fmt.Fprintf(msgOut, "🔓 已解锁: %s\n", strings.Join(ids, ", "))
+	}
+}
+
+// UpgradeModules 升级指定的模块，ids 为空时升级所有模块
+// opts 中的 SkipDeps 和 ConflictPolicy 用于每个模块的安装
+func (r *RMMD) UpgradeModules(ids []string, opts InstallOptions) ([]UpgradeResult, error) {
+	modules, err := r.ListModules()
+	if err != nil {
+		return nil, err
+	}
+
+	holds, err := loadHolds()
+	if err != nil {
+		return nil, err
+	}
+
+	installed := make(map[string]ModuleInfo)
+	for _, module := range modules {
+		installed[module.ID] = module
+	}
+
+	if len(ids) == 0 {
+		for _, module := range modules {
+			ids = append(ids, module.ID)
+		}
+	}
+
+	var results []UpgradeResult
+	var candidates []ModuleInfo
+	for _, id := range ids {
+		module, ok := installed[id]
+		switch {
+		case !ok:
+			results = append(results, UpgradeResult{ID: id, Status: "failed", Reason: "模块未安装"})
+		case holds[id]:
+			results = append(results, UpgradeResult{ID: id, FromVersion: module.Version, Status: "skipped", Reason: "已锁定"})
+		case module.UpdateJSON == "":
+			results = append(results, UpgradeResult{ID: id, FromVersion: module.Version, Status: "skipped", Reason: "没有 updateJson"})
+		default:
+			candidates = append(candidates, module)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return results, nil
+	}
+
+	fmt.Fprintf(msgOut, "🔍 正在检查 %d 个模块的更新...\n", len(candidates))
+	uc := NewUpdateChecker()
+	uc.refresh = true
+	checks := uc.CheckModules(candidates)
+
+	md := NewModuleDownloader()
+	for _, check := range checks {
+		result := UpgradeResult{ID: check.ID, FromVersion: check.Version, ToVersion: check.LatestVersion}
+
+		switch {
+		case check.Error != "":
+			result.Status = "failed"
+			result.Reason = "检查更新失败: " + check.Error
+		case !check.Outdated:
+			result.Status = "skipped"
+			result.Reason = "已是最新版本"
+		default:
+			fmt.Fprintf(msgOut, "\n⬆️  正在升级 %s: %s → %s\n", check.ID, check.Version, check.LatestVersion)
+			if err := r.upgradeModule(md, check, opts); err != nil {
+				result.Status = "failed"
+				result.Reason = err.Error()
+			} else {
+				result.Status = "upgraded"
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// upgradeModule 下载并安装单个模块的新版本
+func (r *RMMD) upgradeModule(md *ModuleDownloader, check UpdateCheckResult, base InstallOptions) error {
+	updateInfo := &UpdateInfo{
+		Version:     check.LatestVersion,
+		VersionCode: check.LatestVersionCode,
+		ZipURL:      check.ZipURL,
+	}
+
+	filePath, err := md.downloadModule(updateInfo)
+	if err != nil {
+		return fmt.Errorf("下载模块失败: %v", err)
+	}
+
+	// update.json 中的 zipUrl 可能填错或被篡改，不能安装成其他模块
+	zipInfo, err := r.ValidateModuleZip(filePath)
+	if err != nil {
+		return err
+	}
+	if zipInfo.ID != check.ID {
+		return fmt.Errorf("下载的模块ID为 %s，与 %s 不一致: %s", zipInfo.ID, check.ID, check.ZipURL)
+	}
+
+	opts := InstallOptions{
+		ConflictPolicy: base.ConflictPolicy,
+		ZipURL:         check.ZipURL,
+		Proxy:          md.lastProxy,
+		Dependencies:   check.Dependencies,
+		SkipDeps:       base.SkipDeps,
+	}
+	if err := r.InstallModuleWithDeps(filePath, opts); err != nil {
+		return fmt.Errorf("安装模块失败: %v", err)
+	}
+	return nil
+}
+
+// printUpgradeSummary 打印升级结果汇总
+func printUpgradeSummary(results []UpgradeResult) {
+	groups := map[string][]UpgradeResult{}
+	for _, result := range results {
+		groups[result.Status] = append(groups[result.Status], result)
+	}
+
+	fmt.Fprintln(msgOut, "\n"+strings.Repeat("━", 60))
+	fmt.Fprintln(msgOut, "📊 升级结果汇总")
+	fmt.Fprintln(msgOut, strings.Repeat("━", 60))
+
+	if upgraded := groups["upgraded"]; len(upgraded) > 0 {
+		fmt.Fprintf(msgOut, "✅ 已升级 (%d):\n", len(upgraded))
+		for _, result := range upgraded {
+			fmt.Fprintf(msgOut, "   %s: %s → %s\n", result.ID, result.FromVersion, result.ToVersion)
+		}
+	}
+	if failed := groups["failed"]; len(failed) > 0 {
+		fmt.Fprintf(msgOut, "❌ 失败 (%d):\n", len(failed))
+		for _, result := range failed {
+			fmt.Fprintf(msgOut, "   %s: %s\n", result.ID, result.Reason)
+		}
+	}
+	if skipped := groups["skipped"]; len(skipped) > 0 {
+		fmt.Fprintf(msgOut, "⏭️  跳过 (%d):\n", len(skipped))
+		for _, result := range skipped {
+			fmt.Fprintf(msgOut, "   %s: %s\n", result.ID, result.Reason)
+		}
+	}
+
+	if len(groups["upgraded"]) > 0 {
+		fmt.Fprintln(msgOut, "🔄 需要重启设备后生效")
+	}
+}
+
+// handleUpgradeCommand 处理 module upgrade 命令
+func handleUpgradeCommand(args []string) {
+	all := false
+	var opts InstallOptions
+	var ids []string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		switch name {
+		case "--all", "-a":
+			all = true
+		case "--skip-deps":
+			opts.SkipDeps = true
+		case "--on-conflict":
+			if !hasValue {
+				if i+1 >= len(args) {
+					fmt.Fprintln(msgOut, "错误: --on-conflict 需要一个参数 (ask, abort, continue, disable)")
+					return
+				}
+				i++
+				value = args[i]
+			}
+			switch value {
+			case ConflictAsk, ConflictAbort, ConflictContinue, ConflictDisable:
+				opts.ConflictPolicy = value
+			default:
+				fmt.Fprintf(msgOut, "错误: 未知的冲突处理策略: %s\n", value)
+				return
+			}
+		default:
+			ids = append(ids, args[i])
+		}
+	}
+
+	if !all && len(ids) == 0 {
+		fmt.Fprintln(msgOut, "错误: 请指定要升级的模块ID，或使用 --all 升级所有模块")
+		fmt.Fprintln(msgOut, "用法: rmmp module upgrade <模块ID>... | --all [--skip-deps] [--on-conflict ask|abort|continue|disable]")
+		return
+	}
+	if all {
+		ids = nil
+	}
+
+	// 批量升级或无法交互时不能停下来询问，默认放弃有冲突的模块，继续升级其他模块
+	if opts.ConflictPolicy == "" && (all || len(ids) > 1 || !stdinIsTerminal()) {
+		opts.ConflictPolicy = ConflictAbort
+	}
+
+	var results []UpgradeResult
+	var err error
+	// 守护进程无法询问用户，需要交互处理冲突时在本地升级
+	var client *DaemonClient
+	if opts.ConflictPolicy != "" && opts.ConflictPolicy != ConflictAsk {
+		client = dialDaemon()
+	}
+	if client != nil {
+		defer client.Close()
+		params := daemonUpgradeParams{IDs: ids, ConflictPolicy: opts.ConflictPolicy, SkipDeps: opts.SkipDeps}
+		err = client.Call("upgrade", params, &results)
+	} else {
+		results, err = NewRMMD().UpgradeModules(ids, opts)
+	}
+	if err != nil {
+		fmt.Fprintf(msgOut, "❌ 升级失败: %v\n", err)
+		return
+	}
+
+	if machineOutput() {
+		if err := printData(results); err != nil {
+			fmt.Fprintf(msgOut, "❌ %v\n", err)
+		}
+		return
+	}
+	printUpgradeSummary(results)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// module.prop 中必须存在的字段
+var requiredModuleProps = []string{"id", "name", "version", "versionCode"}
+
+// ModuleZipInfo 表示校验通过的模块zip包信息
+type ModuleZipInfo struct {
+	Path  string
+	ID    string
+	Props map[string]string
+	Files []string
+	// Dependencies rmmproject.toml 中声明的依赖
+	Dependencies []string
+}
+
+// ValidateModuleZip 在交给Root管理器安装前检查模块zip包
+// 检查 module.prop 及其必需字段、模块ID、安装脚本以及路径穿越
+func (r *RMMD) ValidateModuleZip(zipPath string) (*ModuleZipInfo, error) {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开zip文件: %v", err)
+	}
+	defer reader.Close()
+
+	info := &ModuleZipInfo{Path: zipPath}
+	var problems []string
+	var propFile, projectFile *zip.File
+	hasInstaller := false
+
+	for _, file := range reader.File {
+		info.Files = append(info.Files, file.Name)
+
+		if isUnsafeZipPath(file.Name) {
+			problems = append(problems, fmt.Sprintf("包含不安全的路径: %s", file.Name))
+			continue
+		}
+
+		switch file.Name {
+		case "module.prop":
+			propFile = file
+		case "rmmproject.toml":
+			projectFile = file
+		case "META-INF/com/google/android/update-binary", "customize.sh":
+			hasInstaller = true
+		}
+	}
+
+	if !hasInstaller {
+		problems = append(problems, "缺少 META-INF/com/google/android/update-binary 或 customize.sh")
+	}
+
+	if propFile == nil {
+		problems = append(problems, "缺少 module.prop")
+	} else {
+		props, err := r.readZipProperties(propFile)
+		if err != nil {
+			problems = append(problems, err.Error())
+		} else {
+			info.Props = props
+			info.ID = props["id"]
+			for _, key := range requiredModuleProps {
+				if props[key] == "" {
+					problems = append(problems, fmt.Sprintf("module.prop 缺少必需字段: %s", key))
+				}
+			}
+			if info.ID != "" && !moduleIDPattern.MatchString(info.ID) {
+				problems = append(problems, fmt.Sprintf("无效的模块ID: %s", info.ID))
+			}
+		}
+	}
+
+	if projectFile != nil {
+		deps, err := readZipDependencies(projectFile)
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+		for _, spec := range deps {
+			if _, err := parseDependency(spec); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+		info.Dependencies = deps
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("模块校验失败:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+
+	return info, nil
+}
+
+// readZipProperties 读取并解析zip包中的 module.prop
+func (r *RMMD) readZipProperties(file *zip.File) (map[string]string, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("读取 module.prop 失败: %v", err)
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("读取 module.prop 失败: %v", err)
+	}
+
+	return ParseModuleProp(string(content)).Map(), nil
+}
+
+// isUnsafeZipPath 判断zip条目路径是否可能逃逸出解压目录
+func isUnsafeZipPath(name string) bool {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") {
+		return true
+	}
+	// Windows 盘符，如 C:/
+	if len(name) > 1 && name[1] == ':' {
+		return true
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsUnsafeZipPath(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"module.prop", false},
+		{"system/bin/foo", false},
+		{"system/", false},
+		{"./customize.sh", false},
+		{"a/..b/c", false},
+		{"../evil", true},
+		{"system/../../evil", true},
+		{"system/..", true},
+		{"/etc/passwd", true},
+		{"..\\evil", true},
+		{"system\\..\\..\\evil", true},
+		{"C:/evil", true},
+		{"c:evil", true},
+	}
+
+	for _, tt := range tests {
+		if got := isUnsafeZipPath(tt.name); got != tt.want {
+			t.Errorf("isUnsafeZipPath(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateModuleZip(t *testing.T) {
+	const prop = "id=demo\nname=Demo\nversion=v1\nversionCode=1\n"
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantID  string
+		wantErr string
+	}{
+		{
+			name:   "customize.sh",
+			files:  map[string]string{"module.prop": prop, "customize.sh": ""},
+			wantID: "demo",
+		},
+		{
+			name:   "update-binary",
+			files:  map[string]string{"module.prop": prop, "META-INF/com/google/android/update-binary": ""},
+			wantID: "demo",
+		},
+		{
+			name:    "missing installer",
+			files:   map[string]string{"module.prop": prop},
+			wantErr: "缺少 META-INF/com/google/android/update-binary 或 customize.sh",
+		},
+		{
+			name:    "missing module.prop",
+			files:   map[string]string{"customize.sh": ""},
+			wantErr: "缺少 module.prop",
+		},
+		{
+			name:    "missing required field",
+			files:   map[string]string{"module.prop": "id=demo\nname=Demo\nversion=v1\n", "customize.sh": ""},
+			wantErr: "缺少必需字段: versionCode",
+		},
+		{
+			name:    "invalid id",
+			files:   map[string]string{"module.prop": strings.Replace(prop, "id=demo", "id=../demo", 1), "customize.sh": ""},
+			wantErr: "无效的模块ID",
+		},
+		{
+			name:    "path traversal",
+			files:   map[string]string{"module.prop": prop, "customize.sh": "", "../evil": "x"},
+			wantErr: "包含不安全的路径: ../evil",
+		},
+		{
+			name:    "absolute path",
+			files:   map[string]string{"module.prop": prop, "customize.sh": "", "/etc/evil": "x"},
+			wantErr: "包含不安全的路径: /etc/evil",
+		},
+		{
+			name: "invalid dependency",
+			files: map[string]string{
+				"module.prop":     prop,
+				"customize.sh":    "",
+				"rmmproject.toml": "[project]\ndependencies = [\"1bad\"]\n",
+			},
+			wantErr: "无效的依赖声明",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := (&RMMD{}).ValidateModuleZip(writeTestZip(t, tt.files))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.ID != tt.wantID {
+				t.Errorf("ID = %q, want %q", info.ID, tt.wantID)
+			}
+		})
+	}
+}
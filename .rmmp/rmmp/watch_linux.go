@@ -0,0 +1,107 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	// 模块目录：模块的创建、删除和重命名
+	watchRootMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+	// 模块子目录：还需要关注 module.prop 等文件的修改
+	watchModuleMask = watchRootMask | syscall.IN_CLOSE_WRITE
+)
+
+// watchModules 使用 inotify 监听模块目录和每个模块的子目录，阻塞直到出错
+// 只监听一层子目录，足以发现 disable/remove/update 标记和 module.prop 的变化
+func watchModules(dir string, emit func(ModuleEvent)) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return fmt.Errorf("初始化 inotify 失败: %v", err)
+	}
+	defer syscall.Close(fd)
+
+	rootWD, err := syscall.InotifyAddWatch(fd, dir, watchRootMask)
+	if err != nil {
+		return fmt.Errorf("监听 %s 失败: %v", dir, err)
+	}
+
+	// watch descriptor → 模块目录名
+	modules := make(map[int]string)
+	addModule := func(name string) {
+		wd, err := syscall.InotifyAddWatch(fd, filepath.Join(dir, name), watchModuleMask)
+		if err == nil {
+			modules[wd] = name
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %v", dir, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			addModule(entry.Name())
+		}
+	}
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := syscall.Read(fd, buf)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("读取 inotify 事件失败: %v", err)
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(raw.Len)]
+			name := strings.TrimRight(string(nameBytes), "\x00")
+			offset += syscall.SizeofInotifyEvent + int(raw.Len)
+
+			if raw.Mask&syscall.IN_IGNORED != 0 {
+				delete(modules, int(raw.Wd))
+				continue
+			}
+			if name == "" || strings.HasPrefix(name, ".") {
+				continue
+			}
+
+			event := ModuleEvent{Time: time.Now(), Op: inotifyOp(raw.Mask)}
+			if int(raw.Wd) == rootWD {
+				if raw.Mask&syscall.IN_ISDIR == 0 {
+					continue
+				}
+				event.ID = name
+				if event.Op == "created" {
+					addModule(name)
+				}
+			} else if id, ok := modules[int(raw.Wd)]; ok {
+				event.ID = id
+				event.File = name
+			} else {
+				continue
+			}
+			emit(event)
+		}
+	}
+}
+
+// inotifyOp 将 inotify 事件转换为 created/removed/changed
+func inotifyOp(mask uint32) string {
+	switch {
+	case mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+		return "created"
+	case mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+		return "removed"
+	}
+	return "changed"
+}
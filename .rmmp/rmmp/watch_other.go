@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// watchModules 只在 Linux/Android 上支持
+func watchModules(dir string, emit func(ModuleEvent)) error {
+	return fmt.Errorf("%s 不支持 inotify", runtime.GOOS)
+}
@@ -0,0 +1,10 @@
+#!/system/bin/sh
+MODDIR=${0%/*}
+
+# 等待开机完成后启动 rmmp 守护进程
+until [ "$(getprop sys.boot_completed)" = "1" ]; do
+  sleep 1
+done
+
+mkdir -p /data/adb/rmmp
+"$MODDIR/system/bin/rmmp" daemon >> /data/adb/rmmp/daemon.log 2>&1 &